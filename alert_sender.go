@@ -1,21 +1,41 @@
 package main
 
+import "sync"
+
 type AlertSender struct {
 	Notifiers []Notifier
 
 	MessageCh <-chan Message
 	ErrCh     chan<- error
+
+	mu sync.RWMutex
 }
 
 func (as *AlertSender) SetNotifier(n Notifier) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
 	as.Notifiers = append(as.Notifiers, n)
 }
 
+// SetNotifiers replaces the notifiers. Messages being sent finish with the
+// previous ones.
+func (as *AlertSender) SetNotifiers(ns []Notifier) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.Notifiers = ns
+}
+
+// Run sends each message to every notifier. It returns once MessageCh is
+// closed and the queued messages have been sent.
 func (as *AlertSender) Run() {
-	for {
-		msg := <-as.MessageCh
+	for msg := range as.MessageCh {
+		as.mu.RLock()
+		notifiers := as.Notifiers
+		as.mu.RUnlock()
 
-		for _, notifier := range as.Notifiers {
+		for _, notifier := range notifiers {
 			if err := notifier.Notify(msg); err != nil {
 				as.ErrCh <- err
 			}
@@ -32,7 +32,7 @@ func TestAlertSender_Run(t *testing.T) {
 	messageCh := make(chan Message)
 	errCh := make(chan error)
 
-	dummyMessage := Message{"dummy message", Critical}
+	dummyMessage := Message{Text: "dummy message", StatusType: Critical}
 	notifierMock := new(NotifierMock)
 
 	notifierMock.On("Notify", dummyMessage).Return(nil)
@@ -54,7 +54,7 @@ func TestAlertSender_Run_notify_error(t *testing.T) {
 	messageCh := make(chan Message)
 	errCh := make(chan error)
 
-	dummyMessage := Message{"dummy message", Critical}
+	dummyMessage := Message{Text: "dummy message", StatusType: Critical}
 	notifierMock := new(NotifierMock)
 
 	notifierMock.On("Notify", dummyMessage).Return(fmt.Errorf("error"))
@@ -74,3 +74,25 @@ func TestAlertSender_Run_notify_error(t *testing.T) {
 	err := <-errCh
 	assert.NotNil(t, err)
 }
+
+func TestAlertSender_Run_close(t *testing.T) {
+	messageCh := make(chan Message, 2)
+	errCh := make(chan error)
+
+	messages := []Message{{Text: "first message", StatusType: Critical}, {Text: "second message", StatusType: OK}}
+	notifierMock := new(NotifierMock)
+	for _, msg := range messages {
+		notifierMock.On("Notify", msg).Return(nil)
+		messageCh <- msg
+	}
+	close(messageCh)
+
+	alertSender := &AlertSender{
+		Notifiers: []Notifier{notifierMock},
+		MessageCh: messageCh,
+		ErrCh:     errCh,
+	}
+	alertSender.Run()
+
+	notifierMock.AssertExpectations(t)
+}
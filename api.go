@@ -3,11 +3,16 @@ package main
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
+// reloadConfig reloads the configuration for POST /api/v1/reload. It is nil
+// until the monitors are running.
+var reloadConfig func() (*MonitorDiff, error)
+
 type HTTPServer struct {
 	*echo.Echo
 }
@@ -19,6 +24,9 @@ func NewHTTPServer() *HTTPServer {
 	apiv1 := e.Group("/api/v1")
 	apiv1.GET("/monitors", GetMonitors)
 	apiv1.GET("/results/:id", GetResults)
+	apiv1.GET("/samples/:id", GetSamples)
+	apiv1.POST("/push/:token", Push)
+	apiv1.POST("/reload", Reload)
 
 	return &HTTPServer{e}
 }
@@ -29,6 +37,18 @@ func GetMonitors(c echo.Context) error {
 		return err
 	}
 
+	now := time.Now()
+	for _, monitor := range m {
+		if monitor.CertNotAfter != nil {
+			days := daysUntil(*monitor.CertNotAfter, now)
+			monitor.CertDaysLeft = &days
+		}
+		if w, ok := workers.Get(monitor.ID); ok {
+			state := w.State()
+			monitor.State = &state
+		}
+	}
+
 	return c.JSON(http.StatusOK, m) 
 }
 
@@ -47,3 +67,58 @@ func GetResults(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, r)	
 }
+
+func GetSamples(c echo.Context) error {
+	id := c.Param("id")
+
+	i, err := strconv.ParseInt(id, 10, 0)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+	if s := c.QueryParam("since"); s != "" {
+		since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+		}
+	}
+
+	s, err := GetSamplesByMonitorID(int(i), since.UTC())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, s)
+}
+
+func Push(c echo.Context) error {
+	p, ok := pushProbes.Get(c.Param("token"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "unknown push token")
+	}
+
+	switch status := c.QueryParam("status"); status {
+	case "", "ok":
+		p.Ping(true, c.QueryParam("msg"))
+	case "fail":
+		p.Ping(false, c.QueryParam("msg"))
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "status must be ok or fail")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func Reload(c echo.Context) error {
+	if reloadConfig == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "reload is not available")
+	}
+
+	diff, err := reloadConfig()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"changes": diff.String()})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMonitors_state(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	worker := &Worker{
+		Status:        OK,
+		Monitor:       monitor,
+		pendingStatus: Critical,
+		pendingCount:  2,
+	}
+	workers.Register(worker)
+	defer workers.Unregister(worker)
+
+	srv := NewHTTPServer()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/monitors", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var got []*Monitor
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal("decode response failed:", err)
+	}
+	assert.Equal(t, &MonitorState{Status: "OK", PendingStatus: "CRITICAL", PendingCount: 2}, got[0].State)
+	assert.Nil(t, got[1].State)
+}
+
+func TestGetMonitors_request(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	monitor.Headers = StringMap{"X-Api-Key": "secret"}
+	monitor.Query = StringMap{"api_key": "querysecret"}
+	monitor.Body = `{"password": "bodysecret"}`
+	if err := UpdateMonitor(monitor.ID, monitor); err != nil {
+		t.Fatal("update monitor failed:", err)
+	}
+
+	srv := NewHTTPServer()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/monitors", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "secret")
+	assert.NotContains(t, rec.Body.String(), "X-Api-Key")
+	assert.NotContains(t, rec.Body.String(), "querysecret")
+	assert.NotContains(t, rec.Body.String(), "bodysecret")
+}
+
+func TestPush(t *testing.T) {
+	monitor := &Monitor{
+		Name:      "nightly backup",
+		Type:      MonitorTypePush,
+		PushToken: "test-push-token",
+		Interval:  Duration(24 * time.Hour),
+	}
+	probe := NewPushProbe(monitor)
+	pushProbes.Register(probe)
+	defer pushProbes.Unregister(probe)
+
+	srv := NewHTTPServer()
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+
+		wantCode   int
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name:   "success",
+			method: http.MethodPost,
+			target: "/api/v1/push/test-push-token",
+
+			wantCode:   http.StatusNoContent,
+			wantResult: true,
+			wantReason: "ping received",
+		},
+		{
+			name:   "failure with message",
+			method: http.MethodPost,
+			target: "/api/v1/push/test-push-token?status=fail&msg=pg_dump%20exited%20with%201",
+
+			wantCode:   http.StatusNoContent,
+			wantResult: false,
+			wantReason: "pg_dump exited with 1",
+		},
+		{
+			name:   "success with status",
+			method: http.MethodPost,
+			target: "/api/v1/push/test-push-token?status=ok",
+
+			wantCode:   http.StatusNoContent,
+			wantResult: true,
+			wantReason: "ping received",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.target, nil)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.wantCode, rec.Code)
+
+			result, reason, err := probe.Check(context.TODO())
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestPush_error(t *testing.T) {
+	probe := NewPushProbe(&Monitor{PushToken: "test-push-token"})
+	pushProbes.Register(probe)
+	defer pushProbes.Unregister(probe)
+
+	srv := NewHTTPServer()
+
+	cases := []struct {
+		name     string
+		method   string
+		target   string
+		wantCode int
+	}{
+		{name: "unknown token", method: http.MethodPost, target: "/api/v1/push/unknown", wantCode: http.StatusNotFound},
+		{name: "invalid status", method: http.MethodPost, target: "/api/v1/push/test-push-token?status=maybe", wantCode: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, target: "/api/v1/push/test-push-token", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.target, nil)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	srv := NewHTTPServer()
+
+	cases := []struct {
+		name       string
+		reload     func() (*MonitorDiff, error)
+		wantCode   int
+		wantResult string
+	}{
+		{
+			name:     "not available",
+			reload:   nil,
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "success",
+			reload: func() (*MonitorDiff, error) {
+				return &MonitorDiff{Added: []string{"new"}}, nil
+			},
+			wantCode:   http.StatusOK,
+			wantResult: `{"changes":"added [\"new\"]"}`,
+		},
+		{
+			name: "failure",
+			reload: func() (*MonitorDiff, error) {
+				return nil, errors.New("invalid config")
+			},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reloadConfig = c.reload
+			defer func() { reloadConfig = nil }()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/reload", nil)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			assert.Equal(t, c.wantCode, rec.Code)
+			if c.wantResult != "" {
+				assert.JSONEq(t, c.wantResult, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	OperatorEquals      = "equals"
+	OperatorNotEquals   = "not_equals"
+	OperatorGreaterThan = "greater_than"
+	OperatorExists      = "exists"
+)
+
+// Assertion checks a value in a JSON response body. Path is a dot-separated
+// list of object keys and array indexes, e.g. "db.healthy" or "items.0.id".
+type Assertion struct {
+	Path     string      `json:"path" toml:"path"`
+	Operator string      `json:"operator" toml:"operator"`
+	Value    interface{} `json:"value,omitempty" toml:"value"`
+}
+
+func (a *Assertion) Validate() error {
+	if a.Path == "" {
+		return fmt.Errorf("assert: path is required")
+	}
+
+	switch a.Operator {
+	case OperatorEquals, OperatorNotEquals:
+		if a.Value == nil {
+			return fmt.Errorf("assert %q: value is required for %s", a.Path, a.Operator)
+		}
+	case OperatorGreaterThan:
+		if _, ok := toFloat(a.Value); !ok {
+			return fmt.Errorf("assert %q: value must be a number for %s", a.Path, a.Operator)
+		}
+	case OperatorExists:
+	default:
+		return fmt.Errorf("assert %q: unknown operator %q", a.Path, a.Operator)
+	}
+
+	return nil
+}
+
+// Evaluate reports whether the assertion holds for the decoded JSON document.
+// When it does not, the returned string describes the failure.
+func (a *Assertion) Evaluate(doc interface{}) (bool, string) {
+	got, found := lookupJSONPath(doc, a.Path)
+	if !found {
+		return false, fmt.Sprintf("json %q not found", a.Path)
+	}
+
+	switch a.Operator {
+	case OperatorEquals:
+		if !jsonEqual(got, a.Value) {
+			return false, fmt.Sprintf("json %q is %s, expected %s", a.Path, formatJSON(got), formatJSON(a.Value))
+		}
+	case OperatorNotEquals:
+		if jsonEqual(got, a.Value) {
+			return false, fmt.Sprintf("json %q is %s, expected not %s", a.Path, formatJSON(got), formatJSON(a.Value))
+		}
+	case OperatorGreaterThan:
+		g, ok := toFloat(got)
+		want, _ := toFloat(a.Value)
+		if !ok || g <= want {
+			return false, fmt.Sprintf("json %q is %s, expected greater than %s", a.Path, formatJSON(got), formatJSON(a.Value))
+		}
+	}
+
+	return true, ""
+}
+
+type Assertions []*Assertion
+
+// https://golang.org/pkg/database/sql/driver/#Value
+func (as Assertions) Value() (driver.Value, error) {
+	return jsonValue(as, len(as) == 0)
+}
+
+// https://golang.org/pkg/database/sql/#Scanner
+func (as *Assertions) Scan(value interface{}) error {
+	return scanJSON(value, as, "assertions")
+}
+
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func jsonEqual(got, want interface{}) bool {
+	if g, ok := toFloat(got); ok {
+		w, ok := toFloat(want)
+		return ok && g == w
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func formatJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertion_Evaluate(t *testing.T) {
+	body := []byte(`{"status":"ok","db":{"healthy":false,"connections":12},"replicas":[{"name":"a"}]}`)
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal("unmarshal failed:", err)
+	}
+
+	cases := []struct {
+		name      string
+		assertion *Assertion
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name:       "equals string",
+			assertion:  &Assertion{Path: "status", Operator: OperatorEquals, Value: "ok"},
+			wantResult: true,
+		},
+		{
+			name:       "equals bool mismatch",
+			assertion:  &Assertion{Path: "db.healthy", Operator: OperatorEquals, Value: true},
+			wantResult: false,
+			wantReason: `json "db.healthy" is false, expected true`,
+		},
+		{
+			name:       "equals integer",
+			assertion:  &Assertion{Path: "db.connections", Operator: OperatorEquals, Value: int64(12)},
+			wantResult: true,
+		},
+		{
+			name:       "not equals",
+			assertion:  &Assertion{Path: "status", Operator: OperatorNotEquals, Value: "ok"},
+			wantResult: false,
+			wantReason: `json "status" is "ok", expected not "ok"`,
+		},
+		{
+			name:       "greater than",
+			assertion:  &Assertion{Path: "db.connections", Operator: OperatorGreaterThan, Value: int64(10)},
+			wantResult: true,
+		},
+		{
+			name:       "not greater than",
+			assertion:  &Assertion{Path: "db.connections", Operator: OperatorGreaterThan, Value: 12.5},
+			wantResult: false,
+			wantReason: `json "db.connections" is 12, expected greater than 12.5`,
+		},
+		{
+			name:       "exists in array",
+			assertion:  &Assertion{Path: "replicas.0.name", Operator: OperatorExists},
+			wantResult: true,
+		},
+		{
+			name:       "not exists",
+			assertion:  &Assertion{Path: "replicas.1.name", Operator: OperatorExists},
+			wantResult: false,
+			wantReason: `json "replicas.1.name" not found`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, reason := c.assertion.Evaluate(doc)
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+		})
+	}
+}
+
+func TestAssertion_Validate(t *testing.T) {
+	cases := []struct {
+		name      string
+		assertion *Assertion
+		wantErr   bool
+	}{
+		{name: "equals", assertion: &Assertion{Path: "status", Operator: OperatorEquals, Value: "ok"}, wantErr: false},
+		{name: "exists", assertion: &Assertion{Path: "status", Operator: OperatorExists}, wantErr: false},
+		{name: "no path", assertion: &Assertion{Operator: OperatorExists}, wantErr: true},
+		{name: "no value", assertion: &Assertion{Path: "status", Operator: OperatorEquals}, wantErr: true},
+		{name: "non-numeric greater than", assertion: &Assertion{Path: "n", Operator: OperatorGreaterThan, Value: "1"}, wantErr: true},
+		{name: "unknown operator", assertion: &Assertion{Path: "status", Operator: "matches", Value: "ok"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.assertion.Validate()
+			assert.Equal(t, c.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		auth    *Auth
+		wantErr bool
+	}{
+		{name: "basic", auth: &Auth{Type: AuthBasic, Username: "user", Password: "pass"}, wantErr: false},
+		{name: "bearer", auth: &Auth{Type: AuthBearer, Token: "token"}, wantErr: false},
+		{name: "mtls", auth: &Auth{ClientCert: "cert.pem", ClientKey: "key.pem", CAFile: "ca.pem"}, wantErr: false},
+		{name: "basic without username", auth: &Auth{Type: AuthBasic, Password: "pass"}, wantErr: true},
+		{name: "bearer without token", auth: &Auth{Type: AuthBearer}, wantErr: true},
+		{name: "unknown type", auth: &Auth{Type: "digest"}, wantErr: true},
+		{name: "cert without key", auth: &Auth{ClientCert: "cert.pem"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.auth.Validate()
+			assert.Equal(t, c.wantErr, err != nil)
+		})
+	}
+}
+
+func TestMonitor_MarshalJSON_auth(t *testing.T) {
+	monitor := &Monitor{
+		Name: "secret check",
+		Auth: &Auth{
+			Type:       AuthBasic,
+			Username:   "user",
+			Password:   "s3cr3t-password",
+			Token:      "s3cr3t-token",
+			ClientCert: "/etc/heartilly/client.pem",
+			ClientKey:  "/etc/heartilly/client-key.pem",
+		},
+	}
+
+	b, err := json.Marshal(monitor)
+	if err != nil {
+		t.Fatal("marshal failed:", err)
+	}
+
+	assert.Contains(t, string(b), `"username":"user"`)
+	assert.NotContains(t, string(b), "s3cr3t-password")
+	assert.NotContains(t, string(b), "s3cr3t-token")
+	assert.NotContains(t, string(b), "client-key.pem")
+}
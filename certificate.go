@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/x509"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var defaultCertExpiryDays = Days{30, 14, 3}
+
+// Days is a list of day counts, used for certificate expiry thresholds.
+type Days []int
+
+// https://golang.org/pkg/database/sql/driver/#Value
+func (d Days) Value() (driver.Value, error) {
+	return jsonValue(d, len(d) == 0)
+}
+
+// https://golang.org/pkg/database/sql/#Scanner
+func (d *Days) Scan(value interface{}) error {
+	return scanJSON(value, d, "days")
+}
+
+// daysUntil returns the number of whole days from now until t.
+func daysUntil(t, now time.Time) int {
+	return int(t.Sub(now).Hours() / 24)
+}
+
+// certNotAfter returns the earliest expiry in the peer certificate chain.
+func certNotAfter(certs []*x509.Certificate) time.Time {
+	var notAfter time.Time
+	for _, c := range certs {
+		if notAfter.IsZero() || c.NotAfter.Before(notAfter) {
+			notAfter = c.NotAfter
+		}
+	}
+	return notAfter
+}
+
+// certErrorReason maps certificate verification failures that mean the
+// endpoint is broken, rather than the check itself, to a reason.
+func certErrorReason(err error) (string, bool) {
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) && invalid.Reason == x509.Expired {
+		return "certificate expired", true
+	}
+
+	var hostname x509.HostnameError
+	if errors.As(err, &hostname) {
+		return fmt.Sprintf("certificate hostname mismatch: %s", hostname.Host), true
+	}
+
+	return "", false
+}
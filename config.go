@@ -1,6 +1,13 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
 	gc "github.com/kayac/go-config"
 )
 
@@ -11,7 +18,13 @@ type Config struct {
 }
 
 type Notification struct {
-	Slack *Slack `toml:"slack"`
+	Slack     *Slack     `toml:"slack"`
+	Webhooks  []*Webhook `toml:"webhook"`
+	Email     *Email     `toml:"email"`
+	PagerDuty *PagerDuty `toml:"pagerduty"`
+	Teams     *Teams     `toml:"teams"`
+	Discord   *Discord   `toml:"discord"`
+	Execs     []*Exec    `toml:"exec"`
 }
 
 type Slack struct {
@@ -19,6 +32,43 @@ type Slack struct {
 	Channel string `toml:"channel"`
 }
 
+type Email struct {
+	Host     string   `toml:"host"`
+	Port     int      `toml:"port"`
+	TLS      string   `toml:"tls"`
+	Auth     string   `toml:"auth"`
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	CAFile   string   `toml:"ca_file"`
+	From     string   `toml:"from"`
+	To       []string `toml:"to"`
+}
+
+type PagerDuty struct {
+	RoutingKey string `toml:"routing_key"`
+	URL        string `toml:"url"`
+}
+
+type Teams struct {
+	URL string `toml:"url"`
+}
+
+type Discord struct {
+	URL string `toml:"url"`
+}
+
+type Exec struct {
+	Command []string `toml:"command"`
+	Timeout Duration `toml:"timeout"`
+}
+
+type Webhook struct {
+	URL     string            `toml:"url"`
+	Body    string            `toml:"body"`
+	Headers map[string]string `toml:"headers"`
+	Secret  string            `toml:"secret"`
+}
+
 func LoadConfig(filename string) (*Config, error) {
 	config := Config{}
 
@@ -27,10 +77,211 @@ func LoadConfig(filename string) (*Config, error) {
 	}
 
 	for _, m := range config.Monitors {
+		if m.Type == "" {
+			m.Type = MonitorTypeHTTP
+		}
 		if m.Method == "" {
 			m.Method = "GET"
 		}
+		if m.Interval == 0 {
+			m.Interval = Duration(defaultInterval)
+		}
+		if m.Timeout == 0 {
+			// A short interval shortens the default timeout instead of
+			// failing validation.
+			m.Timeout = Duration(defaultTimeout)
+			if m.Interval < m.Timeout {
+				m.Timeout = m.Interval
+			}
+		}
+		for _, a := range m.Asserts {
+			if a.Operator == "" {
+				a.Operator = OperatorEquals
+			}
+		}
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range config.Monitors {
+		if m.BodyFile == "" {
+			continue
+		}
+
+		path := m.BodyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filename), path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("monitor %q: read body_file failed: %s", m.Name, err)
+		}
+		m.Body = string(b)
 	}
 
 	return &config, nil
 }
+
+func (c *Config) validate() error {
+	names := make(map[string]bool)
+	pushTokens := make(map[string]string)
+
+	for _, m := range c.Monitors {
+		// Monitors are matched to the database by name.
+		if names[m.Name] {
+			return fmt.Errorf("monitor %q: name is already used", m.Name)
+		}
+		names[m.Name] = true
+
+		switch m.Type {
+		case MonitorTypeHTTP:
+		case MonitorTypeTCP:
+			if m.URL.Scheme != "tcp" || (*url.URL)(&m.URL).Port() == "" {
+				return fmt.Errorf("monitor %q: tcp monitor url must be tcp://host:port", m.Name)
+			}
+		case MonitorTypeDNS:
+			if m.URL.Scheme != "dns" || m.dnsName() == "" {
+				return fmt.Errorf("monitor %q: dns monitor url must be dns://[resolver]/name", m.Name)
+			}
+			if _, ok := dnsRecordTypes[m.recordType()]; !ok {
+				return fmt.Errorf("monitor %q: unsupported record_type %q", m.Name, m.RecordType)
+			}
+		case MonitorTypePush:
+			if m.PushToken == "" {
+				return fmt.Errorf("monitor %q: push_token is required for push monitors", m.Name)
+			}
+			if name, ok := pushTokens[m.PushToken]; ok {
+				return fmt.Errorf("monitor %q: push_token is already used by %q", m.Name, name)
+			}
+			pushTokens[m.PushToken] = m.Name
+		case MonitorTypeGRPC:
+			if (m.URL.Scheme != "grpc" && m.URL.Scheme != "grpcs") || (*url.URL)(&m.URL).Port() == "" {
+				return fmt.Errorf("monitor %q: grpc monitor url must be grpc://host:port or grpcs://host:port", m.Name)
+			}
+		default:
+			return fmt.Errorf("monitor %q: unknown type %q", m.Name, m.Type)
+		}
+		if m.Interval < 0 || m.Timeout < 0 || m.Grace < 0 {
+			return fmt.Errorf("monitor %q: interval, timeout and grace must be positive", m.Name)
+		}
+		if m.Timeout > m.Interval {
+			return fmt.Errorf("monitor %q: timeout %s is longer than interval %s", m.Name, m.Timeout.String(), m.Interval.String())
+		}
+		if m.WarnLatency < 0 || m.CritLatency < 0 {
+			return fmt.Errorf("monitor %q: warn_latency and crit_latency must be positive", m.Name)
+		}
+		if m.WarnLatency > 0 && m.CritLatency > 0 && m.WarnLatency >= m.CritLatency {
+			return fmt.Errorf("monitor %q: warn_latency %s must be shorter than crit_latency %s", m.Name, m.WarnLatency.String(), m.CritLatency.String())
+		}
+		if m.FailThreshold < 0 || m.RecoverThreshold < 0 || m.Retries < 0 || m.RetryDelay < 0 {
+			return fmt.Errorf("monitor %q: fail_threshold, recover_threshold, retries and retry_delay must be positive", m.Name)
+		}
+		if m.FlapThreshold < 0 || m.FlapThreshold == 1 || m.FlapWindow < 0 {
+			return fmt.Errorf("monitor %q: flap_threshold must be at least 2 and flap_window must be positive", m.Name)
+		}
+		if m.Type == MonitorTypePush && (m.WarnLatency > 0 || m.CritLatency > 0) {
+			return fmt.Errorf("monitor %q: latency thresholds are not supported for push monitors", m.Name)
+		}
+		if err := m.ExpectedStatus.Validate(); err != nil {
+			return fmt.Errorf("monitor %q: %s", m.Name, err)
+		}
+		if _, err := regexp.Compile(m.BodyRegexp); err != nil {
+			return fmt.Errorf("monitor %q: invalid body_regexp: %s", m.Name, err)
+		}
+		if m.BodyMaxSize < 0 {
+			return fmt.Errorf("monitor %q: body_max_size must be positive", m.Name)
+		}
+		for _, a := range m.Asserts {
+			if err := a.Validate(); err != nil {
+				return fmt.Errorf("monitor %q: %s", m.Name, err)
+			}
+		}
+		if m.Auth != nil {
+			if err := m.Auth.Validate(); err != nil {
+				return fmt.Errorf("monitor %q: %s", m.Name, err)
+			}
+			// Load the TLS material now, so that a reload with a bad file
+			// fails before anything is changed.
+			if _, err := m.Auth.TLSConfig(); err != nil {
+				return fmt.Errorf("monitor %q: %s", m.Name, err)
+			}
+		}
+		for _, d := range m.CertExpiryDays {
+			if d <= 0 {
+				return fmt.Errorf("monitor %q: cert_expiry_days must be positive", m.Name)
+			}
+		}
+		if m.Body != "" && m.BodyFile != "" {
+			return fmt.Errorf("monitor %q: body and body_file are mutually exclusive", m.Name)
+		}
+	}
+
+	if c.Notification != nil {
+		if err := c.Notification.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *Notification) validate() error {
+	for i, w := range n.Webhooks {
+		if !isHTTPURL(w.URL) {
+			return fmt.Errorf("webhook #%d: url must be an http or https url", i+1)
+		}
+		if _, err := newWebhookTemplate(w.Body); err != nil {
+			return fmt.Errorf("webhook #%d: invalid body: %s", i+1, err)
+		}
+	}
+
+	if e := n.Email; e != nil {
+		if e.Host == "" || e.From == "" || len(e.To) == 0 {
+			return errors.New("email: host, from and to are required")
+		}
+		switch e.TLS {
+		case "", EmailTLSStartTLS, EmailTLSImplicit, EmailTLSNone:
+		default:
+			return fmt.Errorf("email: unknown tls %q", e.TLS)
+		}
+		switch e.Auth {
+		case "", EmailAuthPlain, EmailAuthLogin:
+		default:
+			return fmt.Errorf("email: unknown auth %q", e.Auth)
+		}
+	}
+
+	if p := n.PagerDuty; p != nil {
+		if p.RoutingKey == "" {
+			return errors.New("pagerduty: routing_key is required")
+		}
+		if p.URL != "" && !isHTTPURL(p.URL) {
+			return errors.New("pagerduty: url must be an http or https url")
+		}
+	}
+
+	if n.Teams != nil && !isHTTPURL(n.Teams.URL) {
+		return errors.New("teams: url must be an http or https url")
+	}
+	if n.Discord != nil && !isHTTPURL(n.Discord.URL) {
+		return errors.New("discord: url must be an http or https url")
+	}
+
+	for i, e := range n.Execs {
+		if len(e.Command) == 0 || e.Command[0] == "" {
+			return fmt.Errorf("exec #%d: command is required", i+1)
+		}
+		if e.Timeout < 0 {
+			return fmt.Errorf("exec #%d: timeout must be positive", i+1)
+		}
+	}
+
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
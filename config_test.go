@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,10 +35,13 @@ url = "https://example.com/check"
 				},
 				Monitors: []*Monitor{
 					{
-						Name:   "example.com check",
-						Method: "GET",
-						URL:    parseURL(t, "https://example.com/check"),
-						Follow: false,
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
 					},
 				},
 			},
@@ -63,16 +69,22 @@ url = "https://example.com/check2"
 				},
 				Monitors: []*Monitor{
 					{
-						Name:   "example.com check",
-						Method: "GET",
-						URL:    parseURL(t, "https://example.com/check"),
-						Follow: false,
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
 					},
 					{
-						Name:   "example.com check 2",
-						Method: "GET",
-						URL:    parseURL(t, "https://example.com/check2"),
-						Follow: false,
+						Name:     "example.com check 2",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check2"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
 					},
 				},
 			},
@@ -96,10 +108,13 @@ url = "https://example.com/check"
 				},
 				Monitors: []*Monitor{
 					{
-						Name:   "example.com check",
-						Method: "GET",
-						URL:    parseURL(t, "https://example.com/check"),
-						Follow: false,
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
 					},
 				},
 			},
@@ -129,30 +144,1153 @@ follow = true
 				},
 				Monitors: []*Monitor{
 					{
-						Name:   "example.com post check",
-						Method: "POST",
-						URL:    parseURL(t, "https://example.com/check"),
-						Follow: false,
+						Name:     "example.com post check",
+						Type:     "http",
+						Method:   "POST",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
 					},
 					{
-						Name:   "example.com follow check",
-						Method: "GET",
-						URL:    parseURL(t, "https://example.com/check"),
-						Follow: true,
+						Name:     "example.com follow check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   true,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
 					},
 				},
 			},
 		},
+		{
+			name: "interval shorter than default timeout",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com critical check"
+url = "https://example.com/check"
+interval = "10s"
+
+[[monitor]]
+name = "heartbeat"
+type = "push"
+push_token = "token"
+interval = "5s"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com critical check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(10 * time.Second),
+						Timeout:  Duration(10 * time.Second),
+					},
+					{
+						Name:      "heartbeat",
+						Type:      "push",
+						Method:    "GET",
+						PushToken: "token",
+						Interval:  Duration(5 * time.Second),
+						Timeout:   Duration(5 * time.Second),
+					},
+				},
+			},
+		},
+		{
+			name: "interval and timeout",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com critical check"
+url = "https://example.com/check"
+interval = "10s"
+timeout = "5s"
+
+[[monitor]]
+name = "example.com batch check"
+url = "https://example.com/batch"
+interval = "5m"
+timeout = "60s"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com critical check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(10 * time.Second),
+						Timeout:  Duration(5 * time.Second),
+					},
+					{
+						Name:     "example.com batch check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/batch"),
+						Follow:   false,
+						Interval: Duration(5 * time.Minute),
+						Timeout:  Duration(60 * time.Second),
+					},
+				},
+			},
+		},
+		{
+			name: "connection reuse",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+disable_keep_alive = true
+max_idle_conns = 4
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:             "example.com check",
+						Type:             "http",
+						Method:           "GET",
+						URL:              parseURL(t, "https://example.com/check"),
+						Follow:           false,
+						Interval:         Duration(defaultInterval),
+						Timeout:          Duration(defaultTimeout),
+						DisableKeepAlive: true,
+						MaxIdleConns:     4,
+					},
+				},
+			},
+		},
+		{
+			name: "expected status",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com auth check"
+url = "https://example.com/auth"
+expected_status = ["401", "3xx"]
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:           "example.com auth check",
+						Type:           "http",
+						Method:         "GET",
+						URL:            parseURL(t, "https://example.com/auth"),
+						Follow:         false,
+						Interval:       Duration(defaultInterval),
+						Timeout:        Duration(defaultTimeout),
+						ExpectedStatus: ExpectedStatus{"401", "3xx"},
+					},
+				},
+			},
+		},
+		{
+			name: "json assertions",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com health"
+url = "https://example.com/health"
+
+[[monitor.assert]]
+path = "status"
+value = "ok"
+
+[[monitor.assert]]
+path = "db.healthy"
+operator = "equals"
+value = true
+
+[[monitor.assert]]
+path = "db.connections"
+operator = "greater_than"
+value = 0
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com health",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/health"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
+						Asserts: Assertions{
+							{Path: "status", Operator: OperatorEquals, Value: "ok"},
+							{Path: "db.healthy", Operator: OperatorEquals, Value: true},
+							{Path: "db.connections", Operator: OperatorGreaterThan, Value: int64(0)},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "request headers, query and body",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com post check"
+method = "POST"
+url = "https://example.com/api"
+body = '{"ping":true}'
+content_type = "application/json"
+
+[monitor.headers]
+X-Api-Key = "secret"
+
+[monitor.query]
+verbose = "1"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:        "example.com post check",
+						Type:        "http",
+						Method:      "POST",
+						URL:         parseURL(t, "https://example.com/api"),
+						Follow:      false,
+						Interval:    Duration(defaultInterval),
+						Timeout:     Duration(defaultTimeout),
+						Headers:     StringMap{"X-Api-Key": "secret"},
+						Query:       StringMap{"verbose": "1"},
+						Body:        `{"ping":true}`,
+						ContentType: "application/json",
+					},
+				},
+			},
+		},
+		{
+			name: "auth",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com bearer check"
+url = "https://example.com/check"
+
+[monitor.auth]
+type = "bearer"
+token = '{{ env "TEST_SLACK_TOKEN" }}'
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com bearer check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
+						Auth: &Auth{
+							Type:  AuthBearer,
+							Token: "envtoken",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "tcp",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "redis check"
+type = "tcp"
+url = "tcp://redis.example.com:6379"
+payload = "PING\r\n"
+expect_banner = "+PONG"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:         "redis check",
+						Type:         "tcp",
+						Method:       "GET",
+						URL:          parseURL(t, "tcp://redis.example.com:6379"),
+						Follow:       false,
+						Interval:     Duration(defaultInterval),
+						Timeout:      Duration(defaultTimeout),
+						Payload:      "PING\r\n",
+						ExpectBanner: "+PONG",
+					},
+				},
+			},
+		},
+		{
+			name: "dns",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com mx"
+type = "dns"
+url = "dns://8.8.8.8/example.com"
+record_type = "MX"
+expected_answers = ["10 mail.example.com"]
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:            "example.com mx",
+						Type:            "dns",
+						Method:          "GET",
+						URL:             parseURL(t, "dns://8.8.8.8/example.com"),
+						Follow:          false,
+						Interval:        Duration(defaultInterval),
+						Timeout:         Duration(defaultTimeout),
+						RecordType:      "MX",
+						ExpectedAnswers: StringList{"10 mail.example.com"},
+					},
+				},
+			},
+		},
+		{
+			name: "push",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "nightly backup"
+type = "push"
+push_token = "3f1c9b0e"
+interval = "24h"
+grace = "30m"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:      "nightly backup",
+						Type:      "push",
+						Method:    "GET",
+						Follow:    false,
+						Interval:  Duration(24 * time.Hour),
+						Timeout:   Duration(defaultTimeout),
+						PushToken: "3f1c9b0e",
+						Grace:     Duration(30 * time.Minute),
+					},
+				},
+			},
+		},
+		{
+			name: "grpc",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "users service"
+type = "grpc"
+url = "grpcs://users.example.com:443"
+grpc_service = "users.v1.Users"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:        "users service",
+						Type:        "grpc",
+						Method:      "GET",
+						URL:         parseURL(t, "grpcs://users.example.com:443"),
+						Follow:      false,
+						Interval:    Duration(defaultInterval),
+						Timeout:     Duration(defaultTimeout),
+						GRPCService: "users.v1.Users",
+					},
+				},
+			},
+		},
+		{
+			name: "webhook",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[notification.webhook]]
+url = "https://bot.example.com/hooks/heartilly"
+body = '{"text": [[ json .Text ]]}'
+secret = "secret"
+
+[notification.webhook.headers]
+X-Api-Key = "key"
+
+[[notification.webhook]]
+url = "https://hooks.example.com/"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Webhooks: []*Webhook{
+						{
+							URL:     "https://bot.example.com/hooks/heartilly",
+							Body:    `{"text": [[ json .Text ]]}`,
+							Headers: map[string]string{"X-Api-Key": "key"},
+							Secret:  "secret",
+						},
+						{
+							URL: "https://hooks.example.com/",
+						},
+					},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
+					},
+				},
+			},
+		},
+		{
+			name: "email",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.email]
+host = "smtp.example.com"
+port = 465
+tls = "implicit"
+auth = "login"
+username = "heartilly"
+password = "secret"
+from = "heartilly@example.com"
+to = ["ops@example.com", "oncall@example.com"]
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Email: &Email{
+						Host:     "smtp.example.com",
+						Port:     465,
+						TLS:      "implicit",
+						Auth:     "login",
+						Username: "heartilly",
+						Password: "secret",
+						From:     "heartilly@example.com",
+						To:       []string{"ops@example.com", "oncall@example.com"},
+					},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
+					},
+				},
+			},
+		},
+		{
+			name: "pagerduty",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.pagerduty]
+routing_key = "key"
+url = "http://127.0.0.1:8080/v2/enqueue"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					PagerDuty: &PagerDuty{
+						RoutingKey: "key",
+						URL:        "http://127.0.0.1:8080/v2/enqueue",
+					},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
+					},
+				},
+			},
+		},
+		{
+			name: "teams and discord",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.teams]
+url = "https://example.webhook.office.com/webhookb2/heartilly"
+
+[notification.discord]
+url = "https://discord.com/api/webhooks/1/heartilly"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Teams:   &Teams{URL: "https://example.webhook.office.com/webhookb2/heartilly"},
+					Discord: &Discord{URL: "https://discord.com/api/webhooks/1/heartilly"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
+					},
+				},
+			},
+		},
+		{
+			name: "exec",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[notification.exec]]
+command = ["/usr/local/bin/notify-incident", "--team", "ops"]
+timeout = "5s"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Execs: []*Exec{
+						{
+							Command: []string{"/usr/local/bin/notify-incident", "--team", "ops"},
+							Timeout: Duration(5 * time.Second),
+						},
+					},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:     "example.com check",
+						Type:     "http",
+						Method:   "GET",
+						URL:      parseURL(t, "https://example.com/check"),
+						Follow:   false,
+						Interval: Duration(defaultInterval),
+						Timeout:  Duration(defaultTimeout),
+					},
+				},
+			},
+		},
+		{
+			name: "latency thresholds",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+warn_latency = "500ms"
+crit_latency = "2s"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:        "example.com check",
+						Type:        "http",
+						Method:      "GET",
+						URL:         parseURL(t, "https://example.com/check"),
+						Follow:      false,
+						Interval:    Duration(defaultInterval),
+						Timeout:     Duration(defaultTimeout),
+						WarnLatency: Duration(500 * time.Millisecond),
+						CritLatency: Duration(2 * time.Second),
+					},
+				},
+			},
+		},
+		{
+			name: "confirmation",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.slack]
+token = "dummytoken"
+channel = "#general"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+fail_threshold = 3
+recover_threshold = 2
+retries = 1
+retry_delay = "2s"
+flap_threshold = 5
+flap_window = "30m"
+`),
+			want: &Config{
+				DBFile: "/var/lib/heartilly.db",
+				Notification: &Notification{
+					Slack: &Slack{Token: "dummytoken", Channel: "#general"},
+				},
+				Monitors: []*Monitor{
+					{
+						Name:             "example.com check",
+						Type:             "http",
+						Method:           "GET",
+						URL:              parseURL(t, "https://example.com/check"),
+						Follow:           false,
+						Interval:         Duration(defaultInterval),
+						Timeout:          Duration(defaultTimeout),
+						FailThreshold:    3,
+						RecoverThreshold: 2,
+						Retries:          1,
+						RetryDelay:       Duration(2 * time.Second),
+						FlapThreshold:    5,
+						FlapWindow:       Duration(30 * time.Minute),
+					},
+				},
+			},
+		},
+	}
+
+	if err := os.Setenv("TEST_SLACK_TOKEN", "envtoken"); err != nil {
+		t.Fatal("set env failed", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create temporary directory failed", err)
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := os.CreateTemp(tmpDir, "")
+			if err != nil {
+				t.Fatal("create temporary file failed", err)
+			}
+
+			if err := os.WriteFile(f.Name(), c.config, os.ModeTemporary); err != nil {
+				t.Fatal("write file failed", err)
+			}
+
+			config, err := LoadConfig(f.Name())
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, config)
+		})
+	}
+}
+
+func TestLoadConfig_bodyFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create temporary directory failed", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "body.json"), []byte(`{"ping":true}`), 0644); err != nil {
+		t.Fatal("write file failed", err)
+	}
+
+	config := []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com post check"
+method = "POST"
+url = "https://example.com/api"
+body_file = "body.json"
+`)
+	filename := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(filename, config, 0644); err != nil {
+		t.Fatal("write file failed", err)
+	}
+
+	got, err := LoadConfig(filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ping":true}`, got.Monitors[0].Body)
+}
+
+func TestLoadConfig_authTLS(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create temporary directory failed", err)
 	}
+	defer os.RemoveAll(tmpDir)
 
-	if err := os.Setenv("TEST_SLACK_TOKEN", "envtoken"); err != nil {
-		t.Fatal("set env failed", err)
+	notAfter := time.Now().Add(24 * time.Hour)
+	ca := newTestCert(t, tmpDir, "ca", nil, notAfter)
+	client := newTestCert(t, tmpDir, "client", ca, notAfter)
+
+	config := []byte(fmt.Sprintf(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com mtls check"
+url = "https://example.com/check"
+
+[monitor.auth]
+client_cert = %q
+client_key = %q
+ca_file = %q
+`, client.CertFile, client.KeyFile, ca.CertFile))
+	filename := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(filename, config, 0644); err != nil {
+		t.Fatal("write file failed", err)
+	}
+
+	got, err := LoadConfig(filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &Auth{ClientCert: client.CertFile, ClientKey: client.KeyFile, CAFile: ca.CertFile}, got.Monitors[0].Auth)
+}
+
+func TestLoadConfig_invalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		config []byte
+	}{
+		{
+			name: "timeout longer than interval",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+interval = "10s"
+timeout = "30s"
+`),
+		},
+		{
+			name: "invalid expected status",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+expected_status = ["2xx", "ok"]
+`),
+		},
+		{
+			name: "invalid body regexp",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+body_regexp = "(unclosed"
+`),
+		},
+		{
+			name: "invalid assertion",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+
+[[monitor.assert]]
+path = "status"
+operator = "matches"
+value = "ok"
+`),
+		},
+		{
+			name: "body and body_file",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+method = "POST"
+url = "https://example.com/check"
+body = "{}"
+body_file = "body.json"
+`),
+		},
+		{
+			name: "missing body_file",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+method = "POST"
+url = "https://example.com/check"
+body_file = "/nonexistent/body.json"
+`),
+		},
+		{
+			name: "invalid auth",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+
+[monitor.auth]
+type = "basic"
+password = "pass"
+`),
+		},
+		{
+			name: "invalid cert expiry days",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+cert_expiry_days = [30, 0]
+`),
+		},
+		{
+			name: "unknown type",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+type = "icmp"
+url = "https://example.com/check"
+`),
+		},
+		{
+			name: "tcp without port",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "redis check"
+type = "tcp"
+url = "tcp://redis.example.com"
+`),
+		},
+		{
+			name: "dns without name",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "dns check"
+type = "dns"
+url = "dns://8.8.8.8"
+`),
+		},
+		{
+			name: "unsupported record type",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "dns check"
+type = "dns"
+url = "dns://8.8.8.8/example.com"
+record_type = "SRV"
+`),
+		},
+		{
+			name: "push without token",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "nightly backup"
+type = "push"
+`),
+		},
+		{
+			name: "duplicate push token",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "nightly backup"
+type = "push"
+push_token = "token"
+
+[[monitor]]
+name = "hourly sync"
+type = "push"
+push_token = "token"
+`),
+		},
+		{
+			name: "grpc with http url",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "users service"
+type = "grpc"
+url = "https://users.example.com:443"
+`),
+		},
+		{
+			name: "timeout longer than default interval",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+timeout = "2m"
+`),
+		},
+		{
+			name: "warn latency not shorter than crit latency",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+warn_latency = "2s"
+crit_latency = "1s"
+`),
+		},
+		{
+			name: "negative latency",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+warn_latency = "-1s"
+`),
+		},
+		{
+			name: "push with latency threshold",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "hourly sync"
+type = "push"
+push_token = "token"
+warn_latency = "1s"
+`),
+		},
+		{
+			name: "negative fail threshold",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+fail_threshold = -1
+`),
+		},
+		{
+			name: "flap threshold of one",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+flap_threshold = 1
+`),
+		},
+		{
+			name: "webhook without url",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[notification.webhook]]
+body = '{"text": [[ json .Text ]]}'
+`),
+		},
+		{
+			name: "webhook with invalid body",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[notification.webhook]]
+url = "https://hooks.example.com/"
+body = '{"text": [[ json .Text ]'
+`),
+		},
+		{
+			name: "email without recipients",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.email]
+host = "smtp.example.com"
+from = "heartilly@example.com"
+`),
+		},
+		{
+			name: "email with unknown tls",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.email]
+host = "smtp.example.com"
+tls = "ssl"
+from = "heartilly@example.com"
+to = ["ops@example.com"]
+`),
+		},
+		{
+			name: "pagerduty without routing key",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.pagerduty]
+url = "http://127.0.0.1:8080/v2/enqueue"
+`),
+		},
+		{
+			name: "discord without url",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[notification.discord]
+`),
+		},
+		{
+			name: "exec without command",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[notification.exec]]
+timeout = "5s"
+`),
+		},
+		{
+			name: "duplicate name",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check/v2"
+`),
+		},
+		{
+			name: "missing client certificate",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+
+[monitor.auth]
+client_cert = "/nonexistent/client.pem"
+client_key = "/nonexistent/client-key.pem"
+`),
+		},
+		{
+			name: "missing ca file",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+
+[monitor.auth]
+ca_file = "/nonexistent/ca.pem"
+`),
+		},
+		{
+			name: "malformed interval",
+			config: []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "example.com check"
+url = "https://example.com/check"
+interval = "ten seconds"
+`),
+		},
 	}
 
 	tmpDir, err := os.MkdirTemp("", "")
 	if err != nil {
 		t.Fatal("create temporary directory failed", err)
 	}
+	defer os.RemoveAll(tmpDir)
+
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			f, err := os.CreateTemp(tmpDir, "")
@@ -164,9 +1302,8 @@ follow = true
 				t.Fatal("write file failed", err)
 			}
 
-			config, err := LoadConfig(f.Name())
-			assert.Nil(t, err)
-			assert.Equal(t, c.want, config)
+			_, err = LoadConfig(f.Name())
+			assert.NotNil(t, err)
 		})
 	}
 }
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -20,13 +26,87 @@ func OpenDB(dbfile string) error {
 	  name TEXT UNIQUE,
 	  method TEXT,
 	  url TEXT, 
-	  follow INTEGER
+	  follow INTEGER,
+	  interval TEXT DEFAULT '1m0s',
+	  timeout TEXT DEFAULT '15s',
+	  disable_keep_alive INTEGER DEFAULT 0,
+	  max_idle_conns INTEGER DEFAULT 0,
+	  expected_status TEXT,
+	  body_contains TEXT DEFAULT '',
+	  body_not_contains TEXT DEFAULT '',
+	  body_regexp TEXT DEFAULT '',
+	  body_max_size INTEGER DEFAULT 0,
+	  asserts TEXT,
+	  headers TEXT,
+	  query TEXT,
+	  body TEXT DEFAULT '',
+	  content_type TEXT DEFAULT '',
+	  cert_expiry_days TEXT,
+	  cert_not_after TIMESTAMP,
+	  type TEXT DEFAULT 'http',
+	  payload TEXT DEFAULT '',
+	  expect_banner TEXT DEFAULT '',
+	  record_type TEXT DEFAULT '',
+	  expected_answers TEXT,
+	  push_token TEXT DEFAULT '',
+	  grace TEXT DEFAULT '0s',
+	  grpc_service TEXT DEFAULT '',
+	  warn_latency TEXT DEFAULT '0s',
+	  crit_latency TEXT DEFAULT '0s',
+	  fail_threshold INTEGER DEFAULT 0,
+	  recover_threshold INTEGER DEFAULT 0,
+	  retries INTEGER DEFAULT 0,
+	  retry_delay TEXT DEFAULT '0s',
+	  flap_threshold INTEGER DEFAULT 0,
+	  flap_window TEXT DEFAULT '0s',
+	  archived_at TIMESTAMP
 	);
 	`
 	if _, err := db.Exec(createMonitor); err != nil {
 		return err
 	}
 
+	// Columns added after the initial schema. Databases created by older
+	// versions are migrated in place.
+	monitorColumns := []column{
+		{"interval", "TEXT DEFAULT '1m0s'"},
+		{"timeout", "TEXT DEFAULT '15s'"},
+		{"disable_keep_alive", "INTEGER DEFAULT 0"},
+		{"max_idle_conns", "INTEGER DEFAULT 0"},
+		{"expected_status", "TEXT"},
+		{"body_contains", "TEXT DEFAULT ''"},
+		{"body_not_contains", "TEXT DEFAULT ''"},
+		{"body_regexp", "TEXT DEFAULT ''"},
+		{"body_max_size", "INTEGER DEFAULT 0"},
+		{"asserts", "TEXT"},
+		{"headers", "TEXT"},
+		{"query", "TEXT"},
+		{"body", "TEXT DEFAULT ''"},
+		{"content_type", "TEXT DEFAULT ''"},
+		{"cert_expiry_days", "TEXT"},
+		{"cert_not_after", "TIMESTAMP"},
+		{"type", "TEXT DEFAULT 'http'"},
+		{"payload", "TEXT DEFAULT ''"},
+		{"expect_banner", "TEXT DEFAULT ''"},
+		{"record_type", "TEXT DEFAULT ''"},
+		{"expected_answers", "TEXT"},
+		{"push_token", "TEXT DEFAULT ''"},
+		{"grace", "TEXT DEFAULT '0s'"},
+		{"grpc_service", "TEXT DEFAULT ''"},
+		{"warn_latency", "TEXT DEFAULT '0s'"},
+		{"crit_latency", "TEXT DEFAULT '0s'"},
+		{"fail_threshold", "INTEGER DEFAULT 0"},
+		{"recover_threshold", "INTEGER DEFAULT 0"},
+		{"retries", "INTEGER DEFAULT 0"},
+		{"retry_delay", "TEXT DEFAULT '0s'"},
+		{"flap_threshold", "INTEGER DEFAULT 0"},
+		{"flap_window", "TEXT DEFAULT '0s'"},
+		{"archived_at", "TIMESTAMP"},
+	}
+	if err := addMissingColumns("monitor", monitorColumns); err != nil {
+		return err
+	}
+
 	createResult := `
 	CREATE TABLE IF NOT EXISTS result (
 	  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
@@ -40,14 +120,73 @@ func OpenDB(dbfile string) error {
 	if _, err := db.Exec(createResult); err != nil {
 		return err
 	}
+
+	createSample := `
+	CREATE TABLE IF NOT EXISTS sample (
+	  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	  checked_at TIMESTAMP,
+	  status TEXT,
+	  code INTEGER DEFAULT 0,
+	  latency_ms INTEGER DEFAULT 0,
+	  bytes INTEGER DEFAULT 0,
+	  error_class TEXT DEFAULT '',
+	  monitor_id INTEGER,
+	  FOREIGN KEY(monitor_id) REFERENCES monitor(id)
+	);
+	CREATE INDEX IF NOT EXISTS sample_monitor_id_checked_at ON sample(monitor_id, checked_at);
+	`
+	if _, err := db.Exec(createSample); err != nil {
+		return err
+	}
+	return nil
+}
+
+func CloseDB() error {
+	return db.Close()
+}
+
+type column struct {
+	name       string
+	definition string
+}
+
+func addMissingColumns(table string, columns []column) error {
+	var existing []string
+	query := fmt.Sprintf(`SELECT name FROM pragma_table_info('%s')`, table)
+	if err := db.Select(&existing, query); err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		found := false
+		for _, name := range existing {
+			if name == c.name {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		alter := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, c.name, c.definition)
+		if _, err := db.Exec(alter); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func GetAllMonitors() ([]*Monitor, error) {
+	return getAllMonitors(db)
+}
+
+func getAllMonitors(q sqlx.Queryer) ([]*Monitor, error) {
 	var monitors []*Monitor
 
-	query := `SELECT * FROM monitor`
-	if err := db.Select(&monitors, query); err != nil {
+	query := `SELECT * FROM monitor WHERE archived_at IS NULL`
+	if err := sqlx.Select(q, &monitors, query); err != nil {
 		return nil, err
 	}
 
@@ -55,35 +194,126 @@ func GetAllMonitors() ([]*Monitor, error) {
 }
 
 func GetMonitorByName(name string) (*Monitor, error) {
+	return getMonitorByName(db, name)
+}
+
+func getMonitorByName(q sqlx.Queryer, name string) (*Monitor, error) {
 	query := `SELECT * FROM monitor WHERE name = ?`
 	monitor := Monitor{}
 
-	if err := db.Get(&monitor, query, name); err != nil {
+	if err := sqlx.Get(q, &monitor, query, name); err != nil {
 		return nil, err
 	}
 
 	return &monitor, nil
 }
 
+// monitorConfigColumns are the monitor columns set from the configuration,
+// in the order of the values returned by monitorConfigValues.
+var monitorConfigColumns = []string{
+	"name", "method", "url", "follow", "interval", "timeout",
+	"disable_keep_alive", "max_idle_conns", "expected_status",
+	"body_contains", "body_not_contains", "body_regexp", "body_max_size", "asserts",
+	"headers", "query", "body", "content_type", "cert_expiry_days",
+	"type", "payload", "expect_banner", "record_type", "expected_answers",
+	"push_token", "grace", "grpc_service", "warn_latency", "crit_latency",
+	"fail_threshold", "recover_threshold", "retries", "retry_delay",
+	"flap_threshold", "flap_window",
+}
+
+func monitorConfigValues(m *Monitor) []interface{} {
+	return []interface{}{
+		m.Name, m.Method, m.URL.String(), m.Follow, m.Interval.String(), m.Timeout.String(),
+		m.DisableKeepAlive, m.MaxIdleConns, m.ExpectedStatus,
+		m.BodyContains, m.BodyNotContains, m.BodyRegexp, m.BodyMaxSize, m.Asserts,
+		m.Headers, m.Query, m.Body, m.ContentType, m.CertExpiryDays,
+		m.Type, m.Payload, m.ExpectBanner, m.RecordType, m.ExpectedAnswers,
+		m.PushToken, m.Grace.String(), m.GRPCService, m.WarnLatency.String(), m.CritLatency.String(),
+		m.FailThreshold, m.RecoverThreshold, m.Retries, m.RetryDelay.String(),
+		m.FlapThreshold, m.FlapWindow.String(),
+	}
+}
+
 func CreateMonitors(monitors []*Monitor) error {
-	tx, err := db.Begin()
+	tx, err := db.Beginx()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := createMonitors(tx, monitors); err != nil {
+		return err
+	}
 
-	stmt, err := tx.Prepare("INSERT INTO monitor(name, method, url, follow) VALUES(?, ?, ?, ?)")
+	return tx.Commit()
+}
+
+func createMonitors(tx *sqlx.Tx, monitors []*Monitor) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(monitorConfigColumns)), ", ")
+	query := fmt.Sprintf(
+		`INSERT INTO monitor(%s) VALUES(%s)`,
+		strings.Join(monitorConfigColumns, ", "),
+		placeholders,
+	)
+	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
 
 	for _, m := range monitors {
-		_, err = stmt.Exec(m.Name, m.Method, m.URL.String(), m.Follow)
+		_, err = stmt.Exec(monitorConfigValues(m)...)
 		if err != nil {
 			return err
 		}
 	}
 
-	return tx.Commit()
+	return nil
+}
+
+// UpdateMonitor overwrites the configuration of the monitor with the given ID
+// and restores it if it was archived.
+func UpdateMonitor(id int64, m *Monitor) error {
+	return updateMonitor(db, id, m)
+}
+
+func updateMonitor(e sqlx.Execer, id int64, m *Monitor) error {
+	query := fmt.Sprintf(
+		`UPDATE monitor SET %s = ?, archived_at = NULL WHERE id = ?`,
+		strings.Join(monitorConfigColumns, " = ?, "),
+	)
+
+	if _, err := e.Exec(query, append(monitorConfigValues(m), id)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveMonitor hides a monitor from GetAllMonitors while keeping its
+// results and samples.
+func ArchiveMonitor(id int64, archivedAt time.Time) error {
+	return archiveMonitor(db, id, archivedAt)
+}
+
+func archiveMonitor(e sqlx.Execer, id int64, archivedAt time.Time) error {
+	query := `UPDATE monitor SET archived_at = ? WHERE id = ?`
+
+	if _, err := e.Exec(query, archivedAt, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func UpdateMonitorCertNotAfter(id int64, notAfter time.Time) error {
+	query := `UPDATE monitor SET cert_not_after = ? WHERE id = ?`
+
+	if _, err := db.Exec(query, notAfter, id); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func GetResultsByMonitorID(id int) ([]*Result, error) {
@@ -97,6 +327,19 @@ func GetResultsByMonitorID(id int) ([]*Result, error) {
 	return results, nil
 }
 
+// GetLastResultByMonitorID returns the latest status change of a monitor, or
+// sql.ErrNoRows if it has never changed status.
+func GetLastResultByMonitorID(id int64) (*Result, error) {
+	var result Result
+	query := `SELECT * FROM result WHERE monitor_id = ? ORDER BY checked_at DESC, id DESC LIMIT 1`
+
+	if err := db.Get(&result, query, id); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 func CreateResult(result *Result) error {
 	query := `INSERT INTO result(checked_at, status, reason, monitor_id) VALUES(?, ?, ?, ?)`
 
@@ -107,3 +350,76 @@ func CreateResult(result *Result) error {
 
 	return nil
 }
+
+func GetSamplesByMonitorID(id int, since time.Time) ([]*Sample, error) {
+	var samples []*Sample
+	query := `SELECT * FROM sample WHERE monitor_id = ? AND checked_at >= ? ORDER BY checked_at`
+
+	if err := db.Select(&samples, query, id, since); err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+func CreateSamples(samples []*Sample) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO sample(
+	  checked_at, status, code, latency_ms, bytes, error_class, monitor_id
+	) VALUES(?, ?, ?, ?, ?, ?, ?)
+	`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range samples {
+		_, err = stmt.Exec(s.CheckedAt, s.Status, s.Code, s.LatencyMS, s.Bytes, s.ErrorClass, s.MonitorID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// jsonValue encodes v as JSON for a TEXT column, or NULL if it is empty.
+func jsonValue(v interface{}, empty bool) (driver.Value, error) {
+	if empty {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Value(string(b)), nil
+}
+
+// scanJSON decodes a TEXT column written by jsonValue into dst. NULL or an
+// empty string leaves dst nil.
+func scanJSON(value interface{}, dst interface{}, name string) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("unsupported %s value: %v", name, value)
+	}
+
+	if len(b) == 0 {
+		b = []byte("null")
+	}
+
+	return json.Unmarshal(b, dst)
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/go-testfixtures/testfixtures/v3"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -57,6 +59,56 @@ func TestOpenDB(t *testing.T) {
 
 	_, err = db.Queryx(`SELECT * FROM sqlite_master WHERE name = "result"`)
 	assert.Nil(t, err)
+
+	_, err = db.Queryx(`SELECT * FROM sqlite_master WHERE name = "sample"`)
+	assert.Nil(t, err)
+}
+
+func TestOpenDB_migrate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create tempdir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbfile := fmt.Sprintf("%s/heartilly_test.db", dir)
+
+	old, err := sqlx.Open("sqlite3", dbfile)
+	if err != nil {
+		t.Fatal("open db failed:", err)
+	}
+	oldSchema := `
+	CREATE TABLE monitor (
+	  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	  name TEXT UNIQUE,
+	  method TEXT,
+	  url TEXT,
+	  follow INTEGER
+	);
+	INSERT INTO monitor(name, method, url, follow) VALUES("GET /old", "GET", "http://example.com/old", 0);
+	`
+	if _, err := old.Exec(oldSchema); err != nil {
+		t.Fatal("create old schema failed:", err)
+	}
+	old.Close()
+
+	err = OpenDB(dbfile)
+	assert.Nil(t, err)
+
+	got, err := GetMonitorByName("GET /old")
+	assert.Nil(t, err)
+
+	want := &Monitor{
+		ID:       1,
+		Name:     "GET /old",
+		Type:     "http",
+		Method:   "GET",
+		URL:      parseURL(t, "http://example.com/old"),
+		Follow:   false,
+		Interval: Duration(1 * time.Minute),
+		Timeout:  Duration(15 * time.Second),
+	}
+	assert.Equal(t, want, got)
 }
 
 func TestGetMonitors(t *testing.T) {
@@ -69,25 +121,34 @@ func TestGetMonitors(t *testing.T) {
 
 	want := []*Monitor{
 		{
-			ID:     1,
-			Name:   "GET /monitor/get",
-			Method: "GET",
-			URL:    parseURL(t, "http://example.com/monitor/get"),
-			Follow: false,
+			ID:       1,
+			Name:     "GET /monitor/get",
+			Type:     "http",
+			Method:   "GET",
+			URL:      parseURL(t, "http://example.com/monitor/get"),
+			Follow:   false,
+			Interval: Duration(1 * time.Minute),
+			Timeout:  Duration(15 * time.Second),
 		},
 		{
-			ID:     2,
-			Name:   "POST /monitor/post",
-			Method: "POST",
-			URL:    parseURL(t, "http://example.com/monitor/post"),
-			Follow: false,
+			ID:       2,
+			Name:     "POST /monitor/post",
+			Type:     "http",
+			Method:   "POST",
+			URL:      parseURL(t, "http://example.com/monitor/post"),
+			Follow:   false,
+			Interval: Duration(1 * time.Minute),
+			Timeout:  Duration(15 * time.Second),
 		},
 		{
-			ID:     3,
-			Name:   "GET /monitor/follow",
-			Method: "GET",
-			URL:    parseURL(t, "http://example.com/monitor/follow"),
-			Follow: true,
+			ID:       3,
+			Name:     "GET /monitor/follow",
+			Type:     "http",
+			Method:   "GET",
+			URL:      parseURL(t, "http://example.com/monitor/follow"),
+			Follow:   true,
+			Interval: Duration(1 * time.Minute),
+			Timeout:  Duration(15 * time.Second),
 		},
 	}
 
@@ -105,31 +166,40 @@ func TestGetMonitorByName(t *testing.T) {
 		{
 			name: "GET /monitor/get",
 			want: &Monitor{
-				ID:     1,
-				Name:   "GET /monitor/get",
-				Method: "GET",
-				URL:    parseURL(t, "http://example.com/monitor/get"),
-				Follow: false,
+				ID:       1,
+				Name:     "GET /monitor/get",
+				Type:     "http",
+				Method:   "GET",
+				URL:      parseURL(t, "http://example.com/monitor/get"),
+				Follow:   false,
+				Interval: Duration(1 * time.Minute),
+				Timeout:  Duration(15 * time.Second),
 			},
 		},
 		{
 			name: "POST /monitor/post",
 			want: &Monitor{
-				ID:     2,
-				Name:   "POST /monitor/post",
-				Method: "POST",
-				URL:    parseURL(t, "http://example.com/monitor/post"),
-				Follow: false,
+				ID:       2,
+				Name:     "POST /monitor/post",
+				Type:     "http",
+				Method:   "POST",
+				URL:      parseURL(t, "http://example.com/monitor/post"),
+				Follow:   false,
+				Interval: Duration(1 * time.Minute),
+				Timeout:  Duration(15 * time.Second),
 			},
 		},
 		{
 			name: "GET /monitor/follow",
 			want: &Monitor{
-				ID:     3,
-				Name:   "GET /monitor/follow",
-				Method: "GET",
-				URL:    parseURL(t, "http://example.com/monitor/follow"),
-				Follow: true,
+				ID:       3,
+				Name:     "GET /monitor/follow",
+				Type:     "http",
+				Method:   "GET",
+				URL:      parseURL(t, "http://example.com/monitor/follow"),
+				Follow:   true,
+				Interval: Duration(1 * time.Minute),
+				Timeout:  Duration(15 * time.Second),
 			},
 		},
 	}
@@ -144,6 +214,150 @@ func TestGetMonitorByName(t *testing.T) {
 	}
 }
 
+func TestCreateMonitors(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create tempdir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbfile := fmt.Sprintf("%s/heartilly_test.db", dir)
+	if err = OpenDB(dbfile); err != nil {
+		t.Fatal("open db failed:", err)
+	}
+
+	monitors := []*Monitor{
+		{
+			Name:           "GET /monitor/fast",
+			Type:           "http",
+			Method:         "GET",
+			URL:            parseURL(t, "http://example.com/monitor/fast"),
+			Follow:         false,
+			Interval:       Duration(10 * time.Second),
+			Timeout:        Duration(3 * time.Second),
+			ExpectedStatus: ExpectedStatus{"200", "3xx"},
+			BodyContains:   "ok",
+			BodyRegexp:     `"healthy":\s*true`,
+			BodyMaxSize:    4096,
+			Asserts: Assertions{
+				{Path: "status", Operator: OperatorEquals, Value: "ok"},
+				{Path: "db.healthy", Operator: OperatorExists},
+			},
+			Headers:        StringMap{"X-Api-Key": "secret"},
+			Query:          StringMap{"verbose": "1"},
+			Body:           `{"ping":true}`,
+			ContentType:    "application/json",
+			CertExpiryDays: Days{21, 7},
+		},
+	}
+	err = CreateMonitors(monitors)
+
+	assert.Nil(t, err)
+
+	got, err := GetAllMonitors()
+	if err != nil {
+		t.Fatal("query failed:", err)
+	}
+
+	monitors[0].ID = 1
+	assert.Equal(t, monitors, got)
+}
+
+func TestUpdateMonitorCertNotAfter(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	notAfter, err := time.Parse("2006-01-02 15:04:05 +0000", "2030-01-02 15:04:05 +0000")
+	if err != nil {
+		t.Fatal("parse time failed:", err)
+	}
+
+	err = UpdateMonitorCertNotAfter(2, notAfter)
+
+	assert.Nil(t, err)
+
+	got, err := GetMonitorByName("POST /monitor/post")
+	if err != nil {
+		t.Fatal("query failed:", err)
+	}
+
+	assert.Equal(t, &notAfter, got.CertNotAfter)
+}
+
+func TestCreateMonitors_tcp(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create tempdir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbfile := fmt.Sprintf("%s/heartilly_test.db", dir)
+	if err = OpenDB(dbfile); err != nil {
+		t.Fatal("open db failed:", err)
+	}
+
+	monitors := []*Monitor{
+		{
+			Name:         "redis",
+			Type:         "tcp",
+			Method:       "GET",
+			URL:          parseURL(t, "tcp://redis.example.com:6379"),
+			Interval:     Duration(10 * time.Second),
+			Timeout:      Duration(3 * time.Second),
+			Payload:      "PING\r\n",
+			ExpectBanner: "+PONG",
+		},
+	}
+	err = CreateMonitors(monitors)
+
+	assert.Nil(t, err)
+
+	got, err := GetAllMonitors()
+	if err != nil {
+		t.Fatal("query failed:", err)
+	}
+
+	monitors[0].ID = 1
+	assert.Equal(t, monitors, got)
+}
+
+func TestCreateMonitors_dns(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create tempdir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbfile := fmt.Sprintf("%s/heartilly_test.db", dir)
+	if err = OpenDB(dbfile); err != nil {
+		t.Fatal("open db failed:", err)
+	}
+
+	monitors := []*Monitor{
+		{
+			Name:            "example.com txt",
+			Type:            "dns",
+			Method:          "GET",
+			URL:             parseURL(t, "dns://8.8.8.8/example.com"),
+			Interval:        Duration(10 * time.Second),
+			Timeout:         Duration(3 * time.Second),
+			RecordType:      "TXT",
+			ExpectedAnswers: StringList{"v=spf1 include:_spf.example.com, -all"},
+		},
+	}
+	err = CreateMonitors(monitors)
+
+	assert.Nil(t, err)
+
+	got, err := GetAllMonitors()
+	if err != nil {
+		t.Fatal("query failed:", err)
+	}
+
+	monitors[0].ID = 1
+	assert.Equal(t, monitors, got)
+}
+
 func TestGetResults(t *testing.T) {
 	cleanup := prepareTestDB(t)
 	defer cleanup()
@@ -279,6 +493,13 @@ func TestGetResults(t *testing.T) {
 					Reason:    "200 OK",
 					MonitorID: 3,
 				},
+				{
+					ID:        16,
+					CheckedAt: baseTime.Add(20 * time.Second).Add(5 * time.Minute),
+					Status:    "CRITICAL",
+					Reason:    "500 Internal Server Error",
+					MonitorID: 3,
+				},
 			},
 		},
 	}
@@ -293,6 +514,63 @@ func TestGetResults(t *testing.T) {
 	}
 }
 
+func TestGetLastResultByMonitorID(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	baseTime, err := time.Parse("2006-01-02 15:04:05 +0000", "2006-01-02 15:04:05 +0000")
+	if err != nil {
+		t.Fatal("parse time failed:", err)
+	}
+
+	cases := []struct {
+		name      string
+		monitorID int64
+		want      *Result
+	}{
+		{
+			name:      "monitorID: 1",
+			monitorID: 1,
+			want: &Result{
+				ID:        13,
+				CheckedAt: baseTime.Add(4 * time.Minute),
+				Status:    "OK",
+				Reason:    "200 OK",
+				MonitorID: 1,
+			},
+		},
+		{
+			name:      "monitorID: 3",
+			monitorID: 3,
+			want: &Result{
+				ID:        16,
+				CheckedAt: baseTime.Add(20 * time.Second).Add(5 * time.Minute),
+				Status:    "CRITICAL",
+				Reason:    "500 Internal Server Error",
+				MonitorID: 3,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := GetLastResultByMonitorID(c.monitorID)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestGetLastResultByMonitorID_noRows(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	_, err := GetLastResultByMonitorID(100)
+
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
 func TestCreateResult(t *testing.T) {
 	dir, err := os.MkdirTemp("", "")
 	if err != nil {
@@ -336,3 +614,64 @@ func TestCreateResult(t *testing.T) {
 	assert.Equal(t, want, got)
 
 }
+
+func TestCreateSamples(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	baseTime, err := time.Parse("2006-01-02 15:04:05 +0000", "2006-01-02 15:04:05 +0000")
+	if err != nil {
+		t.Fatal("parse time failed:", err)
+	}
+
+	samples := []*Sample{
+		{CheckedAt: baseTime, Status: "OK", Code: 200, LatencyMS: 120, Bytes: 512, MonitorID: 1},
+		{CheckedAt: baseTime.Add(1 * time.Minute), Status: "CRITICAL", LatencyMS: 15000, ErrorClass: ErrorClassTimeout, MonitorID: 1},
+		{CheckedAt: baseTime.Add(1 * time.Minute), Status: "OK", Code: 204, LatencyMS: 80, MonitorID: 2},
+		{CheckedAt: baseTime.Add(2 * time.Minute), Status: "WARNING", Code: 200, LatencyMS: 900, Bytes: 512, ErrorClass: ErrorClassLatency, MonitorID: 1},
+	}
+	err = CreateSamples(samples)
+
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name      string
+		monitorID int
+		since     time.Time
+		want      []*Sample
+	}{
+		{
+			name:      "all",
+			monitorID: 1,
+			since:     baseTime,
+			want: []*Sample{
+				{ID: 1, CheckedAt: baseTime, Status: "OK", Code: 200, LatencyMS: 120, Bytes: 512, MonitorID: 1},
+				{ID: 2, CheckedAt: baseTime.Add(1 * time.Minute), Status: "CRITICAL", LatencyMS: 15000, ErrorClass: ErrorClassTimeout, MonitorID: 1},
+				{ID: 4, CheckedAt: baseTime.Add(2 * time.Minute), Status: "WARNING", Code: 200, LatencyMS: 900, Bytes: 512, ErrorClass: ErrorClassLatency, MonitorID: 1},
+			},
+		},
+		{
+			name:      "since",
+			monitorID: 1,
+			since:     baseTime.Add(90 * time.Second),
+			want: []*Sample{
+				{ID: 4, CheckedAt: baseTime.Add(2 * time.Minute), Status: "WARNING", Code: 200, LatencyMS: 900, Bytes: 512, ErrorClass: ErrorClassLatency, MonitorID: 1},
+			},
+		},
+		{
+			name:      "no samples",
+			monitorID: 3,
+			since:     baseTime,
+			want:      nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := GetSamplesByMonitorID(c.monitorID, c.since)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
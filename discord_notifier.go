@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var discordColors = map[string]int{
+	"good":    0x2eb886,
+	"danger":  0xa30200,
+	"warning": 0xdaa038,
+}
+
+// DiscordNotifier posts an embed to a Discord incoming webhook.
+type DiscordNotifier struct {
+	URL string
+
+	client *http.Client
+}
+
+func NewDiscordNotifier(url string) *DiscordNotifier {
+	return &DiscordNotifier{
+		URL:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+type discordMessage struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title     string         `json:"title"`
+	Color     int            `json:"color"`
+	Fields    []discordField `json:"fields"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+func (d *DiscordNotifier) Notify(msg Message) error {
+	if err := postJSON(d.client, d.URL, d.message(msg)); err != nil {
+		return fmt.Errorf("discord: %s", err)
+	}
+	return nil
+}
+
+func (d *DiscordNotifier) message(msg Message) discordMessage {
+	return discordMessage{
+		Embeds: []discordEmbed{
+			{
+				Title: fmt.Sprintf("%s: %s", msg.Title, msg.MonitorName()),
+				Color: d.color(msg.StatusType),
+				// Discord rejects embeds with empty field values.
+				Fields: []discordField{
+					{Name: "Status", Value: msg.Status(), Inline: true},
+					{Name: "Monitor", Value: discordValue(msg.MonitorName()), Inline: true},
+					{Name: "URL", Value: discordValue(msg.URL())},
+					{Name: "Reason", Value: discordValue(msg.Reason)},
+				},
+				Timestamp: msg.CheckedAt,
+			},
+		},
+	}
+}
+
+func (d *DiscordNotifier) color(status Status) int {
+	if c, ok := discordColors[statusColor(status)]; ok {
+		return c
+	}
+	return 0x808080
+}
+
+func discordValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscordNotifier_Notify(t *testing.T) {
+	ts, reqCh := newCaptureServer(http.StatusNoContent)
+	defer ts.Close()
+
+	n := NewDiscordNotifier(ts.URL)
+
+	msg := newTestMessage(t)
+	msg.Reason = ""
+	err := n.Notify(msg)
+	assert.Nil(t, err)
+
+	req := <-reqCh
+	assert.JSONEq(t, `{
+		"embeds": [{
+			"title": "CRITICAL: example.com check",
+			"color": 10682880,
+			"fields": [
+				{"name": "Status", "value": "CRITICAL", "inline": true},
+				{"name": "Monitor", "value": "example.com check", "inline": true},
+				{"name": "URL", "value": "https://example.com/check"},
+				{"name": "Reason", "value": "-"}
+			],
+			"timestamp": "2006-01-02T15:04:05Z"
+		}]
+	}`, req.body)
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+}
+
+func TestDiscordNotifier_Notify_error(t *testing.T) {
+	ts, _ := newCaptureServer(http.StatusBadRequest)
+	defer ts.Close()
+
+	n := NewDiscordNotifier(ts.URL)
+
+	err := n.Notify(newTestMessage(t))
+	assert.NotNil(t, err)
+}
+
+func TestDiscordNotifier_color(t *testing.T) {
+	cases := []struct {
+		status Status
+		want   int
+	}{
+		{status: OK, want: 0x2eb886},
+		{status: Critical, want: 0xa30200},
+		{status: Unknown, want: 0x808080},
+		{status: Warning, want: 0xdaa038},
+	}
+
+	n := DiscordNotifier{}
+	for _, c := range cases {
+		t.Run(c.status.String(), func(t *testing.T) {
+			got := n.color(c.status)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
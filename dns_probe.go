@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var dnsRecordTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"CNAME": dns.TypeCNAME,
+	"MX":    dns.TypeMX,
+	"TXT":   dns.TypeTXT,
+}
+
+// DNSProbe queries a name against a resolver. The monitor URL has the form
+// dns://resolver[:port]/name; when the resolver is omitted, the first
+// nameserver in /etc/resolv.conf is used.
+type DNSProbe struct {
+	Monitor *Monitor
+
+	client *dns.Client
+}
+
+func NewDNSProbe(m *Monitor) *DNSProbe {
+	return &DNSProbe{
+		Monitor: m,
+		client: &dns.Client{
+			Timeout: time.Duration(m.Timeout),
+		},
+	}
+}
+
+func (p *DNSProbe) Check(ctx context.Context) (bool, string, error) {
+	resolver, err := p.resolver()
+	if err != nil {
+		return false, "error", err
+	}
+
+	recordType := p.Monitor.recordType()
+	qtype := dnsRecordTypes[recordType]
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(p.Monitor.dnsName()), qtype)
+
+	resp, _, err := p.client.ExchangeContext(ctx, msg, resolver)
+	if err != nil {
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			return false, "timeout", nil
+		}
+		return false, "error", err
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return false, dns.RcodeToString[resp.Rcode], nil
+	}
+
+	var answers []string
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype != qtype {
+			continue
+		}
+		answers = append(answers, formatDNSAnswer(rr))
+	}
+	sort.Strings(answers)
+
+	if len(answers) == 0 {
+		return false, fmt.Sprintf("no %s records", recordType), nil
+	}
+
+	if expected := p.Monitor.ExpectedAnswers; len(expected) > 0 {
+		want := make([]string, len(expected))
+		for i, e := range expected {
+			want[i] = normalizeDNSAnswer(recordType, e)
+		}
+		sort.Strings(want)
+
+		if strings.Join(answers, ",") != strings.Join(want, ",") {
+			return false, fmt.Sprintf("%s answer mismatch: got [%s], expected [%s]",
+				recordType,
+				strings.Join(answers, ", "),
+				strings.Join(want, ", "),
+			), nil
+		}
+	}
+
+	return true, fmt.Sprintf("%s %s", recordType, strings.Join(answers, ", ")), nil
+}
+
+func (p *DNSProbe) resolver() (string, error) {
+	host := p.Monitor.URL.Host
+	if host == "" {
+		config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return "", err
+		}
+		if len(config.Servers) == 0 {
+			return "", fmt.Errorf("no nameserver found in /etc/resolv.conf")
+		}
+		return net.JoinHostPort(config.Servers[0], config.Port), nil
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(host, "53"), nil
+	}
+	return host, nil
+}
+
+func formatDNSAnswer(rr dns.RR) string {
+	switch v := rr.(type) {
+	case *dns.A:
+		return v.A.String()
+	case *dns.AAAA:
+		return v.AAAA.String()
+	case *dns.CNAME:
+		return normalizeDNSName(v.Target)
+	case *dns.MX:
+		return fmt.Sprintf("%d %s", v.Preference, normalizeDNSName(v.Mx))
+	case *dns.TXT:
+		return strings.Join(v.Txt, "")
+	}
+	return rr.String()
+}
+
+// normalizeDNSAnswer converts an expected answer to the form produced by
+// formatDNSAnswer, so that e.g. "Example.com." matches "example.com".
+func normalizeDNSAnswer(recordType, s string) string {
+	switch recordType {
+	case "A", "AAAA":
+		if ip := net.ParseIP(s); ip != nil {
+			return ip.String()
+		}
+	case "CNAME":
+		return normalizeDNSName(s)
+	case "MX":
+		if fields := strings.Fields(s); len(fields) == 2 {
+			return fields[0] + " " + normalizeDNSName(fields[1])
+		}
+	}
+	return s
+}
+
+func normalizeDNSName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDNSRecords = map[string][]string{
+	"example.com. A":         {"example.com. 60 IN A 192.0.2.2", "example.com. 60 IN A 192.0.2.1"},
+	"example.com. AAAA":      {"example.com. 60 IN AAAA 2001:db8::1"},
+	"www.example.com. CNAME": {"www.example.com. 60 IN CNAME Example.com."},
+	"example.com. MX":        {"example.com. 60 IN MX 10 mail.example.com."},
+	"example.com. TXT":       {`example.com. 60 IN TXT "v=spf1 -all"`},
+}
+
+func newTestDNSServer(t *testing.T) (string, func()) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+
+		q := r.Question[0]
+		if q.Name == "slow.example.com." {
+			time.Sleep(500 * time.Millisecond)
+		}
+
+		records, ok := testDNSRecords[fmt.Sprintf("%s %s", q.Name, dns.TypeToString[q.Qtype])]
+		if !ok && q.Name != "example.com." && q.Name != "www.example.com." {
+			m.SetRcode(r, dns.RcodeNameError)
+		}
+		for _, record := range records {
+			rr, err := dns.NewRR(record)
+			if err != nil {
+				t.Error("parse record failed:", err)
+				continue
+			}
+			m.Answer = append(m.Answer, rr)
+		}
+
+		w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn:        pc,
+		Handler:           handler,
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go server.ActivateAndServe()
+	<-started
+
+	return pc.LocalAddr().String(), func() { server.Shutdown() }
+}
+
+func TestDNSProbe_Check(t *testing.T) {
+	addr, shutdown := newTestDNSServer(t)
+	defer shutdown()
+
+	cases := []struct {
+		name            string
+		query           string
+		recordType      string
+		expectedAnswers StringList
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name:       "any answer",
+			query:      "example.com",
+			recordType: "",
+
+			wantResult: true,
+			wantReason: "A 192.0.2.1, 192.0.2.2",
+		},
+		{
+			name:            "matching answer set",
+			query:           "example.com",
+			recordType:      "A",
+			expectedAnswers: StringList{"192.0.2.2", "192.0.2.1"},
+
+			wantResult: true,
+			wantReason: "A 192.0.2.1, 192.0.2.2",
+		},
+		{
+			name:            "drifted answer set",
+			query:           "example.com",
+			recordType:      "A",
+			expectedAnswers: StringList{"192.0.2.1"},
+
+			wantResult: false,
+			wantReason: "A answer mismatch: got [192.0.2.1, 192.0.2.2], expected [192.0.2.1]",
+		},
+		{
+			name:            "aaaa",
+			query:           "example.com",
+			recordType:      "AAAA",
+			expectedAnswers: StringList{"2001:0db8::0001"},
+
+			wantResult: true,
+			wantReason: "AAAA 2001:db8::1",
+		},
+		{
+			name:            "cname",
+			query:           "www.example.com",
+			recordType:      "cname",
+			expectedAnswers: StringList{"example.com."},
+
+			wantResult: true,
+			wantReason: "CNAME example.com",
+		},
+		{
+			name:            "mx",
+			query:           "example.com",
+			recordType:      "MX",
+			expectedAnswers: StringList{"10 Mail.example.com"},
+
+			wantResult: true,
+			wantReason: "MX 10 mail.example.com",
+		},
+		{
+			name:            "txt",
+			query:           "example.com",
+			recordType:      "TXT",
+			expectedAnswers: StringList{"v=spf1 -all"},
+
+			wantResult: true,
+			wantReason: "TXT v=spf1 -all",
+		},
+		{
+			name:       "no records",
+			query:      "www.example.com",
+			recordType: "A",
+
+			wantResult: false,
+			wantReason: "no A records",
+		},
+		{
+			name:       "nxdomain",
+			query:      "missing.example.com",
+			recordType: "A",
+
+			wantResult: false,
+			wantReason: "NXDOMAIN",
+		},
+		{
+			name:       "timeout",
+			query:      "slow.example.com",
+			recordType: "A",
+
+			wantResult: false,
+			wantReason: "timeout",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Type:            MonitorTypeDNS,
+				URL:             parseURL(t, fmt.Sprintf("dns://%s/%s", addr, c.query)),
+				Timeout:         Duration(100 * time.Millisecond),
+				RecordType:      c.recordType,
+				ExpectedAnswers: c.expectedAnswers,
+			}
+			probe := NewDNSProbe(monitor)
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestDNSProbe_resolver(t *testing.T) {
+	cases := []struct {
+		url  string
+		want string
+	}{
+		{url: "dns://192.0.2.53/example.com", want: "192.0.2.53:53"},
+		{url: "dns://192.0.2.53:5353/example.com", want: "192.0.2.53:5353"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			probe := NewDNSProbe(&Monitor{URL: parseURL(t, c.url)})
+			got, err := probe.resolver()
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
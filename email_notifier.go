@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EmailTLSStartTLS = "starttls"
+	EmailTLSImplicit = "implicit"
+	EmailTLSNone     = "none"
+
+	EmailAuthPlain = "plain"
+	EmailAuthLogin = "login"
+
+	defaultEmailPort = 587
+	emailTimeout     = 10 * time.Second
+)
+
+var emailHTMLBody = template.Must(template.New("email").Parse(`<html>
+<body>
+<h2>{{ .Title }}: {{ .MonitorName }}</h2>
+<table>
+<tr><th align="left">Status</th><td>{{ .Status }}</td></tr>
+<tr><th align="left">Monitor</th><td>{{ .MonitorName }}</td></tr>
+<tr><th align="left">URL</th><td>{{ .URL }}</td></tr>
+<tr><th align="left">Reason</th><td>{{ .Reason }}</td></tr>
+<tr><th align="left">Checked at</th><td>{{ .CheckedAt.Format "2006-01-02 15:04:05 MST" }}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// EmailNotifier sends multipart text and HTML emails over SMTP.
+type EmailNotifier struct {
+	Host     string
+	Port     int
+	TLS      string
+	Auth     string
+	Username string
+	Password string
+	From     string
+	To       []string
+
+	tlsConfig *tls.Config
+}
+
+func NewEmailNotifier(e *Email) (*EmailNotifier, error) {
+	n := &EmailNotifier{
+		Host:     e.Host,
+		Port:     e.Port,
+		TLS:      e.TLS,
+		Auth:     e.Auth,
+		Username: e.Username,
+		Password: e.Password,
+		From:     e.From,
+		To:       e.To,
+
+		tlsConfig: &tls.Config{ServerName: e.Host},
+	}
+	if n.Port == 0 {
+		n.Port = defaultEmailPort
+	}
+	if n.TLS == "" {
+		n.TLS = EmailTLSStartTLS
+	}
+	if n.Auth == "" {
+		n.Auth = EmailAuthPlain
+	}
+
+	if e.CAFile != "" {
+		ca, err := os.ReadFile(e.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", e.CAFile)
+		}
+		n.tlsConfig.RootCAs = pool
+	}
+
+	return n, nil
+}
+
+func (n *EmailNotifier) Notify(msg Message) error {
+	body, err := n.message(msg)
+	if err != nil {
+		return err
+	}
+
+	c, err := n.dial()
+	if err != nil {
+		return fmt.Errorf("email: %s", err)
+	}
+	defer c.Close()
+
+	if err := n.send(c, body); err != nil {
+		return fmt.Errorf("email: %s", err)
+	}
+	return c.Quit()
+}
+
+func (n *EmailNotifier) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+	dialer := &net.Dialer{Timeout: emailTimeout}
+
+	var conn net.Conn
+	var err error
+	if n.TLS == EmailTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, n.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(emailTimeout))
+
+	c, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if n.TLS == EmailTLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(n.tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (n *EmailNotifier) send(c *smtp.Client, body []byte) error {
+	if n.Username != "" {
+		var auth smtp.Auth
+		switch n.Auth {
+		case EmailAuthLogin:
+			auth = &loginAuth{username: n.Username, password: n.Password}
+		default:
+			auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// message builds a multipart/alternative email with a plain text and an
+// HTML part.
+func (n *EmailNotifier) message(msg Message) ([]byte, error) {
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+
+	text := fmt.Sprintf("%s\r\n\r\nStatus: %s\r\nMonitor: %s\r\nURL: %s\r\nReason: %s\r\n",
+		strings.ReplaceAll(msg.Text, "\n", "\r\n"),
+		msg.Status(),
+		msg.MonitorName(),
+		msg.URL(),
+		msg.Reason,
+	)
+	if err := writeQuotedPrintablePart(mw, "text/plain; charset=UTF-8", []byte(text)); err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err := emailHTMLBody.Execute(&html, msg); err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintablePart(mw, "text/html; charset=UTF-8", html.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", fmt.Sprintf("[heartilly] %s: %s", msg.Title, msg.MonitorName())))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n", mw.Boundary())
+	fmt.Fprintf(&b, "\r\n")
+	b.Write(parts.Bytes())
+
+	return b.Bytes(), nil
+}
+
+func writeQuotedPrintablePart(mw *multipart.Writer, contentType string, body []byte) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	pw, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qw := quotedprintable.NewWriter(pw)
+	if _, err := qw.Write(body); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// loginAuth implements the LOGIN authentication mechanism, which net/smtp
+// does not provide. Like smtp.PlainAuth, it refuses to send credentials over
+// an unencrypted connection to a remote host.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSuffix(string(fromServer), ":")) {
+	case "username":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type smtpSession struct {
+	tls  bool
+	auth []string
+	from string
+	to   []string
+	data string
+}
+
+// testSMTPServer is a minimal SMTP stand-in that accepts a single session
+// and reports what the client sent.
+type testSMTPServer struct {
+	ln         net.Listener
+	tlsConfig  *tls.Config
+	implicit   bool
+	rejectRcpt bool
+	sessionCh  chan smtpSession
+}
+
+func newTestSMTPServer(t *testing.T, tlsConfig *tls.Config, implicit, rejectRcpt bool) *testSMTPServer {
+	t.Helper()
+
+	var ln net.Listener
+	var err error
+	if implicit {
+		ln, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+
+	s := &testSMTPServer{
+		ln:         ln,
+		tlsConfig:  tlsConfig,
+		implicit:   implicit,
+		rejectRcpt: rejectRcpt,
+		sessionCh:  make(chan smtpSession, 1),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *testSMTPServer) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSMTPServer) Close() {
+	s.ln.Close()
+}
+
+func (s *testSMTPServer) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	session := smtpSession{tls: s.implicit}
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tc.PrintfLine("250-localhost")
+			if s.tlsConfig != nil && !session.tls {
+				tc.PrintfLine("250-STARTTLS")
+			}
+			tc.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			tc.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tc = textproto.NewConn(conn)
+			session.tls = true
+		case "AUTH":
+			fields := strings.Fields(arg)
+			switch fields[0] {
+			case "PLAIN":
+				cred, _ := base64.StdEncoding.DecodeString(fields[1])
+				session.auth = append([]string{"PLAIN"}, strings.Split(string(cred), "\x00")...)
+			case "LOGIN":
+				session.auth = []string{"LOGIN"}
+				for _, prompt := range []string{"Username:", "Password:"} {
+					tc.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+					resp, _ := tc.ReadLine()
+					cred, _ := base64.StdEncoding.DecodeString(resp)
+					session.auth = append(session.auth, string(cred))
+				}
+			}
+			tc.PrintfLine("235 authenticated")
+		case "MAIL":
+			session.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tc.PrintfLine("250 ok")
+		case "RCPT":
+			if s.rejectRcpt {
+				tc.PrintfLine("550 no such user")
+				continue
+			}
+			session.to = append(session.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tc.PrintfLine("250 ok")
+		case "DATA":
+			tc.PrintfLine("354 go ahead")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			session.data = string(data)
+			tc.PrintfLine("250 queued")
+		case "QUIT":
+			tc.PrintfLine("221 bye")
+			s.sessionCh <- session
+			return
+		default:
+			tc.PrintfLine("250 ok")
+		}
+	}
+}
+
+func newTestSMTPTLSConfig(t *testing.T, dir string) (*tls.Config, string) {
+	t.Helper()
+
+	ca := newTestCert(t, dir, "ca", nil, time.Now().Add(24*time.Hour))
+	server := newTestCert(t, dir, "server", ca, time.Now().Add(24*time.Hour))
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.Cert.Raw}, PrivateKey: server.Key}},
+	}, ca.CertFile
+}
+
+func TestEmailNotifier_Notify(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create temporary directory failed", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tlsConfig, caFile := newTestSMTPTLSConfig(t, tmpDir)
+
+	cases := []struct {
+		name     string
+		email    Email
+		implicit bool
+
+		wantTLS  bool
+		wantAuth []string
+	}{
+		{
+			name: "starttls with plain auth",
+			email: Email{
+				Username: "heartilly",
+				Password: "secret",
+				CAFile:   caFile,
+			},
+			wantTLS:  true,
+			wantAuth: []string{"PLAIN", "", "heartilly", "secret"},
+		},
+		{
+			name: "implicit tls with login auth",
+			email: Email{
+				TLS:      EmailTLSImplicit,
+				Auth:     EmailAuthLogin,
+				Username: "heartilly",
+				Password: "secret",
+				CAFile:   caFile,
+			},
+			implicit: true,
+			wantTLS:  true,
+			wantAuth: []string{"LOGIN", "heartilly", "secret"},
+		},
+		{
+			name: "plain text without auth",
+			email: Email{
+				TLS: EmailTLSNone,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var serverTLS *tls.Config
+			if c.wantTLS {
+				serverTLS = tlsConfig
+			}
+			s := newTestSMTPServer(t, serverTLS, c.implicit, false)
+			defer s.Close()
+
+			c.email.Host = "127.0.0.1"
+			c.email.Port = s.Port()
+			c.email.From = "heartilly@example.com"
+			c.email.To = []string{"ops@example.com", "oncall@example.com"}
+			n, err := NewEmailNotifier(&c.email)
+			if err != nil {
+				t.Fatal("create notifier failed:", err)
+			}
+
+			err = n.Notify(newTestMessage(t))
+			assert.Nil(t, err)
+
+			session := <-s.sessionCh
+			assert.Equal(t, c.wantTLS, session.tls)
+			assert.Equal(t, c.wantAuth, session.auth)
+			assert.Equal(t, "heartilly@example.com", session.from)
+			assert.Equal(t, []string{"ops@example.com", "oncall@example.com"}, session.to)
+
+			msg, err := mail.ReadMessage(strings.NewReader(session.data))
+			if err != nil {
+				t.Fatal("parse message failed:", err)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			assert.Nil(t, err)
+			assert.Equal(t, "[heartilly] CRITICAL: example.com check", subject)
+			assert.Equal(t, "ops@example.com, oncall@example.com", msg.Header.Get("To"))
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			assert.Nil(t, err)
+			assert.Equal(t, "multipart/alternative", mediaType)
+
+			parts := map[string]string{}
+			mr := multipart.NewReader(msg.Body, params["boundary"])
+			for {
+				p, err := mr.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal("read part failed:", err)
+				}
+				body, _ := io.ReadAll(bufio.NewReader(p))
+				contentType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+				parts[contentType] = string(body)
+			}
+
+			for _, contentType := range []string{"text/plain", "text/html"} {
+				assert.Contains(t, parts[contentType], "CRITICAL")
+				assert.Contains(t, parts[contentType], "example.com check")
+				assert.Contains(t, parts[contentType], "https://example.com/check")
+				assert.Contains(t, parts[contentType], "500 Internal Server Error")
+			}
+			assert.Contains(t, parts["text/html"], "<td>CRITICAL</td>")
+		})
+	}
+}
+
+func TestEmailNotifier_Notify_error(t *testing.T) {
+	s := newTestSMTPServer(t, nil, false, true)
+	defer s.Close()
+
+	n, err := NewEmailNotifier(&Email{
+		Host: "127.0.0.1",
+		Port: s.Port(),
+		TLS:  EmailTLSNone,
+		From: "heartilly@example.com",
+		To:   []string{"nobody@example.com"},
+	})
+	if err != nil {
+		t.Fatal("create notifier failed:", err)
+	}
+
+	err = n.Notify(newTestMessage(t))
+	assert.NotNil(t, err)
+}
+
+func TestEmailNotifier_Notify_startTLSUnsupported(t *testing.T) {
+	s := newTestSMTPServer(t, nil, false, false)
+	defer s.Close()
+
+	n, err := NewEmailNotifier(&Email{
+		Host: "127.0.0.1",
+		Port: s.Port(),
+		From: "heartilly@example.com",
+		To:   []string{"ops@example.com"},
+	})
+	if err != nil {
+		t.Fatal("create notifier failed:", err)
+	}
+
+	err = n.Notify(newTestMessage(t))
+	assert.NotNil(t, err)
+}
+
+func TestNewEmailNotifier_defaults(t *testing.T) {
+	n, err := NewEmailNotifier(&Email{Host: "smtp.example.com", From: "heartilly@example.com", To: []string{"ops@example.com"}})
+	if err != nil {
+		t.Fatal("create notifier failed:", err)
+	}
+
+	assert.Equal(t, defaultEmailPort, n.Port)
+	assert.Equal(t, EmailTLSStartTLS, n.TLS)
+	assert.Equal(t, EmailAuthPlain, n.Auth)
+}
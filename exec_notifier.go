@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const defaultExecTimeout = 30 * time.Second
+
+// ExecNotifier runs a command for each message. The message is passed in
+// HEARTILLY_* environment variables and as JSON on stdin.
+type ExecNotifier struct {
+	Command []string
+	Timeout time.Duration
+}
+
+func NewExecNotifier(e *Exec) *ExecNotifier {
+	n := &ExecNotifier{
+		Command: e.Command,
+		Timeout: time.Duration(e.Timeout),
+	}
+	if n.Timeout == 0 {
+		n.Timeout = defaultExecTimeout
+	}
+	return n
+}
+
+func (e *ExecNotifier) Notify(msg Message) error {
+	stdin, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.Command[0], e.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"HEARTILLY_TITLE="+msg.Title,
+		"HEARTILLY_STATUS="+msg.Status(),
+		"HEARTILLY_MONITOR="+msg.MonitorName(),
+		"HEARTILLY_URL="+msg.URL(),
+		"HEARTILLY_REASON="+msg.Reason,
+	)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", e.Timeout)
+		}
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			return fmt.Errorf("exec %s: %s: %s", e.Command[0], err, s)
+		}
+		return fmt.Errorf("exec %s: %s", e.Command[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecNotifier_Notify(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create temporary directory failed", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	envFile := filepath.Join(tmpDir, "env")
+	stdinFile := filepath.Join(tmpDir, "stdin")
+	n := NewExecNotifier(&Exec{
+		Command: []string{"sh", "-c", `printf '%s\n%s\n%s\n%s\n%s' "$HEARTILLY_TITLE" "$HEARTILLY_STATUS" "$HEARTILLY_MONITOR" "$HEARTILLY_URL" "$HEARTILLY_REASON" > "$0"; cat > "$1"`, envFile, stdinFile},
+	})
+
+	err = n.Notify(newTestMessage(t))
+	assert.Nil(t, err)
+
+	env, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal("read env failed:", err)
+	}
+	assert.Equal(t, "CRITICAL\nCRITICAL\nexample.com check\nhttps://example.com/check\n500 Internal Server Error", string(env))
+
+	stdin, err := os.ReadFile(stdinFile)
+	if err != nil {
+		t.Fatal("read stdin failed:", err)
+	}
+	assert.JSONEq(t, `{
+		"title": "CRITICAL",
+		"status": "CRITICAL",
+		"monitor": "example.com check",
+		"url": "https://example.com/check",
+		"reason": "500 Internal Server Error",
+		"checked_at": "2006-01-02T15:04:05Z",
+		"text": "CRITICAL: example.com check\nhttps://example.com/check - 500 Internal Server Error"
+	}`, string(stdin))
+}
+
+func TestExecNotifier_Notify_error(t *testing.T) {
+	cases := []struct {
+		name string
+		exec Exec
+
+		wantErr string
+	}{
+		{
+			name:    "non-zero exit",
+			exec:    Exec{Command: []string{"sh", "-c", "echo 'token expired' >&2; exit 3"}},
+			wantErr: "exec sh: exit status 3: token expired",
+		},
+		{
+			name:    "timeout",
+			exec:    Exec{Command: []string{"sleep", "5"}, Timeout: Duration(100 * time.Millisecond)},
+			wantErr: "exec sleep: timed out after 100ms",
+		},
+		{
+			name:    "command not found",
+			exec:    Exec{Command: []string{"heartilly-no-such-command"}},
+			wantErr: `exec heartilly-no-such-command: exec: "heartilly-no-such-command": executable file not found in $PATH`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			n := NewExecNotifier(&c.exec)
+
+			err := n.Notify(newTestMessage(t))
+			if assert.NotNil(t, err) {
+				assert.Equal(t, c.wantErr, err.Error())
+			}
+		})
+	}
+}
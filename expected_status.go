@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExpectedStatus is a list of status code patterns a response must match.
+// A pattern is an exact code ("200"), a class ("3xx") or an inclusive range
+// ("200-299"). An empty list accepts any status below 400.
+type ExpectedStatus []string
+
+func (e ExpectedStatus) Validate() error {
+	for _, pattern := range e {
+		if _, _, err := parseStatusPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e ExpectedStatus) Match(code int) bool {
+	if len(e) == 0 {
+		return code < 400
+	}
+
+	for _, pattern := range e {
+		min, max, err := parseStatusPattern(pattern)
+		if err != nil {
+			continue
+		}
+		if min <= code && code <= max {
+			return true
+		}
+	}
+	return false
+}
+
+func (e ExpectedStatus) String() string {
+	return strings.Join(e, ", ")
+}
+
+// https://golang.org/pkg/database/sql/driver/#Value
+func (e ExpectedStatus) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	return driver.Value(strings.Join(e, ",")), nil
+}
+
+// https://golang.org/pkg/database/sql/#Scanner
+func (e *ExpectedStatus) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported expected status value: %v", value)
+	}
+
+	if s == "" {
+		*e = nil
+		return nil
+	}
+
+	*e = ExpectedStatus(strings.Split(s, ","))
+
+	return nil
+}
+
+func parseStatusPattern(pattern string) (int, int, error) {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+
+	if len(p) == 3 && strings.HasSuffix(p, "xx") {
+		class, err := strconv.Atoi(p[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, fmt.Errorf("invalid expected status: %q", pattern)
+		}
+		return class * 100, class*100 + 99, nil
+	}
+
+	if i := strings.Index(p, "-"); i >= 0 {
+		min, err := parseStatusCode(p[:i])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid expected status: %q", pattern)
+		}
+		max, err := parseStatusCode(p[i+1:])
+		if err != nil || max < min {
+			return 0, 0, fmt.Errorf("invalid expected status: %q", pattern)
+		}
+		return min, max, nil
+	}
+
+	code, err := parseStatusCode(p)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid expected status: %q", pattern)
+	}
+	return code, code, nil
+}
+
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("status code out of range: %d", code)
+	}
+	return code, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectedStatus_Match(t *testing.T) {
+	cases := []struct {
+		expected ExpectedStatus
+		code     int
+		want     bool
+	}{
+		{expected: nil, code: 200, want: true},
+		{expected: nil, code: 301, want: true},
+		{expected: nil, code: 404, want: false},
+		{expected: ExpectedStatus{"200", "204"}, code: 204, want: true},
+		{expected: ExpectedStatus{"200", "204"}, code: 201, want: false},
+		{expected: ExpectedStatus{"3xx"}, code: 301, want: true},
+		{expected: ExpectedStatus{"3xx"}, code: 200, want: false},
+		{expected: ExpectedStatus{"401"}, code: 401, want: true},
+		{expected: ExpectedStatus{"200-299"}, code: 250, want: true},
+		{expected: ExpectedStatus{"200-299"}, code: 300, want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v/%d", c.expected, c.code), func(t *testing.T) {
+			assert.Equal(t, c.want, c.expected.Match(c.code))
+		})
+	}
+}
+
+func TestExpectedStatus_Validate(t *testing.T) {
+	cases := []struct {
+		expected ExpectedStatus
+		wantErr  bool
+	}{
+		{expected: ExpectedStatus{"200", "2xx", "200-299"}, wantErr: false},
+		{expected: ExpectedStatus{"ok"}, wantErr: true},
+		{expected: ExpectedStatus{"6xx"}, wantErr: true},
+		{expected: ExpectedStatus{"999"}, wantErr: true},
+		{expected: ExpectedStatus{"299-200"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expected.String(), func(t *testing.T) {
+			err := c.expected.Validate()
+			assert.Equal(t, c.wantErr, err != nil)
+		})
+	}
+}
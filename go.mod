@@ -9,7 +9,9 @@ require (
 	github.com/kayac/go-config v0.5.1
 	github.com/labstack/echo/v4 v4.2.2
 	github.com/mattn/go-sqlite3 v1.14.7
+	github.com/miekg/dns v1.1.43
 	github.com/slack-go/slack v0.8.2
 	github.com/stretchr/testify v1.5.1
 	go.uber.org/zap v1.16.0
+	google.golang.org/grpc v1.38.0
 )
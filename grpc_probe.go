@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// GRPCProbe calls the standard grpc.health.v1.Health/Check method. The
+// monitor URL is grpc://host:port for plaintext or grpcs://host:port for TLS.
+type GRPCProbe struct {
+	Monitor *Monitor
+
+	dialOptions []grpc.DialOption
+}
+
+func NewGRPCProbe(m *Monitor) (*GRPCProbe, error) {
+	var opts []grpc.DialOption
+
+	if m.URL.Scheme == "grpcs" {
+		tlsConfig := &tls.Config{}
+		if m.Auth != nil {
+			c, err := m.Auth.TLSConfig()
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				tlsConfig = c
+			}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if m.Auth != nil && m.Auth.authorization() != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(m.Auth))
+	}
+
+	return &GRPCProbe{
+		Monitor:     m,
+		dialOptions: opts,
+	}, nil
+}
+
+func (p *GRPCProbe) Check(ctx context.Context) (bool, string, error) {
+	if timeout := time.Duration(p.Monitor.Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, err := grpc.DialContext(ctx, p.Monitor.URL.Host, p.dialOptions...)
+	if err != nil {
+		return false, "error", err
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: p.Monitor.GRPCService,
+	})
+	if err != nil {
+		switch grpcstatus.Code(err) {
+		case codes.DeadlineExceeded:
+			return false, "timeout", nil
+		case codes.NotFound:
+			return false, healthpb.HealthCheckResponse_SERVICE_UNKNOWN.String(),
+				fmt.Errorf("service %q is unknown to the health server", p.Monitor.GRPCService)
+		}
+		return false, "error", err
+	}
+
+	switch resp.Status {
+	case healthpb.HealthCheckResponse_SERVING:
+		return true, resp.Status.String(), nil
+	case healthpb.HealthCheckResponse_NOT_SERVING:
+		return false, resp.Status.String(), nil
+	default:
+		return false, resp.Status.String(), fmt.Errorf("health status %s", resp.Status.String())
+	}
+}
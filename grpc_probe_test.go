@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func newTestGRPCServer(t *testing.T, opts ...grpc.ServerOption) (string, func()) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("example.Serving", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("example.NotServing", healthpb.HealthCheckResponse_NOT_SERVING)
+	hs.SetServingStatus("example.Unknown", healthpb.HealthCheckResponse_UNKNOWN)
+
+	s := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(l)
+
+	return l.Addr().String(), s.Stop
+}
+
+func TestGRPCProbe_Check(t *testing.T) {
+	addr, stop := newTestGRPCServer(t)
+	defer stop()
+
+	cases := []struct {
+		name    string
+		service string
+
+		wantResult bool
+		wantReason string
+		wantErr    bool
+	}{
+		{
+			name:    "server",
+			service: "",
+
+			wantResult: true,
+			wantReason: "SERVING",
+			wantErr:    false,
+		},
+		{
+			name:    "serving",
+			service: "example.Serving",
+
+			wantResult: true,
+			wantReason: "SERVING",
+			wantErr:    false,
+		},
+		{
+			name:    "not serving",
+			service: "example.NotServing",
+
+			wantResult: false,
+			wantReason: "NOT_SERVING",
+			wantErr:    false,
+		},
+		{
+			name:    "unknown",
+			service: "example.Unknown",
+
+			wantResult: false,
+			wantReason: "UNKNOWN",
+			wantErr:    true,
+		},
+		{
+			name:    "unregistered service",
+			service: "example.Missing",
+
+			wantResult: false,
+			wantReason: "SERVICE_UNKNOWN",
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Type:        MonitorTypeGRPC,
+				URL:         parseURL(t, fmt.Sprintf("grpc://%s", addr)),
+				Timeout:     Duration(1 * time.Second),
+				GRPCService: c.service,
+			}
+			probe, err := NewGRPCProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Equal(t, c.wantErr, err != nil)
+		})
+	}
+}
+
+func TestGRPCProbe_Check_tls(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create tempdir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	notAfter := time.Now().Add(24 * time.Hour)
+	ca := newTestCert(t, dir, "ca", nil, notAfter)
+	server := newTestCert(t, dir, "server", ca, notAfter)
+
+	creds, err := credentials.NewServerTLSFromFile(server.CertFile, server.KeyFile)
+	if err != nil {
+		t.Fatal("load server certificate failed:", err)
+	}
+	addr, stop := newTestGRPCServer(t, grpc.Creds(creds))
+	defer stop()
+
+	cases := []struct {
+		name string
+		url  string
+		auth *Auth
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name: "tls",
+			url:  fmt.Sprintf("grpcs://%s", addr),
+			auth: &Auth{CAFile: ca.CertFile},
+
+			wantResult: true,
+			wantReason: "SERVING",
+		},
+		{
+			name: "plaintext to tls server",
+			url:  fmt.Sprintf("grpc://%s", addr),
+
+			wantResult: false,
+			wantReason: "error",
+		},
+		{
+			name: "untrusted certificate",
+			url:  fmt.Sprintf("grpcs://%s", addr),
+
+			wantResult: false,
+			wantReason: "error",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Type:    MonitorTypeGRPC,
+				URL:     parseURL(t, c.url),
+				Timeout: Duration(1 * time.Second),
+				Auth:    c.auth,
+			}
+			probe, err := NewGRPCProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, reason, _ := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+		})
+	}
+}
+
+func TestGRPCProbe_Check_auth(t *testing.T) {
+	authCh := make(chan string, 1)
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		authCh <- strings.Join(md.Get("authorization"), ",")
+		return handler(ctx, req)
+	}
+	addr, stop := newTestGRPCServer(t, grpc.UnaryInterceptor(interceptor))
+	defer stop()
+
+	cases := []struct {
+		name string
+		auth *Auth
+
+		want string
+	}{
+		{
+			name: "basic",
+			auth: &Auth{Type: AuthBasic, Username: "user", Password: "pass"},
+			want: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name: "bearer",
+			auth: &Auth{Type: AuthBearer, Token: "token"},
+			want: "Bearer token",
+		},
+		{
+			name: "none",
+			auth: &Auth{},
+			want: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Type:    MonitorTypeGRPC,
+				URL:     parseURL(t, fmt.Sprintf("grpc://%s", addr)),
+				Timeout: Duration(1 * time.Second),
+				Auth:    c.auth,
+			}
+			probe, err := NewGRPCProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, _, err := probe.Check(context.TODO())
+
+			assert.True(t, result)
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, <-authCh)
+		})
+	}
+}
+
+func TestGRPCProbe_Check_unavailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	monitor := &Monitor{
+		Type:    MonitorTypeGRPC,
+		URL:     parseURL(t, fmt.Sprintf("grpc://%s", addr)),
+		Timeout: Duration(1 * time.Second),
+	}
+	probe, err := NewGRPCProbe(monitor)
+	if err != nil {
+		t.Fatal("create probe failed:", err)
+	}
+	result, reason, err := probe.Check(context.TODO())
+
+	assert.False(t, result)
+	assert.Equal(t, "error", reason)
+	assert.NotNil(t, err)
+}
@@ -38,3 +38,7 @@ func (l *Logger) Info(id int, eyesOn, msg string) {
 func (l *Logger) Debug(id int, eyesOn, msg string) {
 	l.baseLogger.Debug(msg, zap.Int("id", id), zap.String("eyes_on", eyesOn))
 }
+
+func (l *Logger) Sync() error {
+	return l.baseLogger.Sync()
+}
@@ -3,142 +3,20 @@ package main
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jessevdk/go-flags"
 )
 
-type Message struct {
-	Text       string
-	StatusType Status
-}
-
-type Worker struct {
-	ID     int
-	Status Status
-
-	Probe *Probe
-
-	MessageCh chan<- Message
-
-	Logger *Logger
-}
-
-func (w *Worker) run(ctx context.Context) {
-	// jitter
-	rand.Seed(time.Now().UnixNano())
-	jitter := rand.Intn(10)
-	time.Sleep(time.Duration(jitter) * time.Second)
-
-	w.Logger.Info(w.ID, w.Probe.Monitor.URL.String(), "start worker")
-
-	c := time.Tick(1 * time.Minute)
-	for {
-		w.Logger.Info(w.ID, w.Probe.Monitor.URL.String(), "check")
-
-		ok, reason, err := w.Probe.Check(ctx)
-
-		result := &Result{
-			CheckedAt:   time.Now().UTC(),
-			Reason:    reason,
-			MonitorID: w.Probe.Monitor.ID,
-		}
-
-		if err == nil {
-			if ok && (!w.Status.Is(OK)) {
-				w.Status.Recovery()
-
-				result.Status = w.Status.String()
-				if err := CreateResult(result); err != nil {
-					w.Logger.Error(
-						w.ID,
-						w.Probe.Monitor.URL.String(),
-						fmt.Sprintf("save result failed: %s", err.Error()),
-					)
-				}
-
-				w.MessageCh <- Message{
-					Text: fmt.Sprintf("%s: %s\n%s - %s",
-						w.Status.String(),
-						w.Probe.Monitor.Name,
-						w.Probe.Monitor.URL.String(),
-						reason,
-					),
-					StatusType: OK,
-				}
-				w.Logger.Info(
-					w.ID,
-					w.Probe.Monitor.URL.String(),
-					fmt.Sprintf("status canged: %s", w.Status.String()),
-				)
-
-			} else if !ok && (w.Status.Is(OK)) {
-				w.Status.Trigger()
-
-				result.Status = w.Status.String()
-				if err := CreateResult(result); err != nil {
-					w.Logger.Error(
-						w.ID,
-						w.Probe.Monitor.URL.String(),
-						fmt.Sprintf("save result failed: %s", err.Error()),
-					)
-				}
-
-				w.MessageCh <- Message{
-					Text: fmt.Sprintf("%s: %s\n%s - %s",
-						w.Status.String(),
-						w.Probe.Monitor.Name,
-						w.Probe.Monitor.URL.String(),
-						reason,
-					),
-					StatusType: Critical,
-				}
-				w.Logger.Info(
-					w.ID,
-					w.Probe.Monitor.URL.String(),
-					fmt.Sprintf("status canged: %s", w.Status.String()),
-				)
-			}
-		} else {
-			if !w.Status.Is(Unknown) {
-				w.Status.Unknown()
-
-				result.Status = w.Status.String()
-				if err := CreateResult(result); err != nil {
-					w.Logger.Error(
-						w.ID,
-						w.Probe.Monitor.URL.String(),
-						fmt.Sprintf("save result failed: %s", err.Error()),
-					)
-				}
-
-				w.MessageCh <- Message{
-					Text: fmt.Sprintf("%s: %s\n%s - %s",
-						w.Status.String(),
-						w.Probe.Monitor.Name,
-						w.Probe.Monitor.URL.String(),
-						reason,
-					),
-					StatusType: Unknown,
-				}
-				w.Logger.Info(
-					w.ID,
-					w.Probe.Monitor.URL.String(),
-					fmt.Sprintf("status canged: %s", w.Status.String()),
-				)
-			}
-		}
-
-		select {
-		case <-c:
-		case <-ctx.Done():
-			return
-		}
-	}
-}
+const (
+	messageQueueSize = 100
+	errorQueueSize   = 100
+	shutdownTimeout  = 30 * time.Second
+)
 
 type Options struct {
 	Config string `short:"c" long:"config" default:"config.toml" description:"configuration file"`
@@ -171,66 +49,128 @@ func main() {
 
 	logger.Info(0, "", fmt.Sprint("open dbfile: ", config.DBFile))
 
-	monitors, err := InitSyncMonitor(config.Monitors)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
-	}
+	messageCh := make(chan Message, messageQueueSize)
+	errCh := make(chan error, errorQueueSize)
+	go func() {
+		for err := range errCh {
+			logger.Error(0, "", err.Error())
+		}
+	}()
 
-	messageCh := make(chan Message)
-	errCh := make(chan error)
 	alertSender := &AlertSender{
 		MessageCh: messageCh,
 		ErrCh:     errCh,
 	}
-	if slackConf := config.Notification.Slack; slackConf != nil {
-		token := slackConf.Token
-		channel := slackConf.Channel
+	alertDone := make(chan struct{})
+	go func() {
+		defer close(alertDone)
+		alertSender.Run()
+	}()
 
-		alertSender.SetNotifier(
-			NewSlackNotifier(token, channel),
-		)
+	sampleCh := make(chan *Sample, defaultSampleBatchSize)
+	sampleWriter := &SampleWriter{
+		SampleCh: sampleCh,
+		ErrCh:    errCh,
 	}
-	go alertSender.Run()
+	sampleDone := make(chan struct{})
+	go func() {
+		defer close(sampleDone)
+		sampleWriter.Run()
+	}()
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	for i, m := range monitors {
-		id := i + 1
+	supervisor := &Supervisor{
+		ConfigFile: opts.Config,
 
-		p := &Probe{
-			Monitor: m,
-		}
+		AlertSender: alertSender,
+		MessageCh:   messageCh,
+		SampleCh:    sampleCh,
 
-		worker := &Worker{
-			ID:     id,
-			Status: OK,
+		Logger: logger,
+	}
+	diff, err := supervisor.Apply(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
 
-			Probe: p,
+	logger.Info(0, "", fmt.Sprint("sync monitors: ", diff.String()))
 
-			MessageCh: messageCh,
-
-			Logger: logger,
-		}
-		go worker.run(ctx)
-	}
+	reloadConfig = supervisor.Reload
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
 
 	httpSrv := NewHTTPServer()
 	go func() {
-		if err := httpSrv.Start(":8000"); err != nil {
+		if err := httpSrv.Start(":8000"); err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
 
+loop:
 	for {
 		select {
-		case err := <-errCh:
-			logger.Error(0, "", err.Error())
+		case <-hupCh:
+			// Reload in the background so that a signal to shut down is
+			// not missed while workers are being restarted.
+			go func() {
+				diff, err := supervisor.Reload()
+				if err != nil {
+					logger.Error(0, "", fmt.Sprintf("reload failed: %s", err.Error()))
+					return
+				}
+				logger.Info(0, "", fmt.Sprint("reload: ", diff.String()))
+			}()
 		case <-ctx.Done():
 			stop()
-			logger.Info(0, "", "Interrupt")
-			os.Exit(0)
+			break loop
 		}
 	}
+
+	logger.Info(0, "", "shutting down")
+	shutdown(supervisor, messageCh, alertDone, sampleCh, sampleDone, httpSrv, logger)
+}
+
+// shutdown stops the workers, flushes the queued notifications and samples,
+// stops the API server and closes the database, all within shutdownTimeout.
+func shutdown(
+	supervisor *Supervisor,
+	messageCh chan<- Message, alertDone <-chan struct{},
+	sampleCh chan<- *Sample, sampleDone <-chan struct{},
+	httpSrv *HTTPServer,
+	logger *Logger,
+) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := supervisor.Shutdown(ctx); err != nil {
+		logger.Error(0, "", fmt.Sprintf("stop workers: %s", err.Error()))
+	}
+
+	// Workers are stopped, so nothing sends on these anymore.
+	close(messageCh)
+	close(sampleCh)
+	select {
+	case <-alertDone:
+	case <-ctx.Done():
+		logger.Error(0, "", fmt.Sprintf("flush notifications: %s", ctx.Err().Error()))
+	}
+	select {
+	case <-sampleDone:
+	case <-ctx.Done():
+		logger.Error(0, "", fmt.Sprintf("flush samples: %s", ctx.Err().Error()))
+	}
+
+	if err := httpSrv.Shutdown(ctx); err != nil {
+		logger.Error(0, "", fmt.Sprintf("stop http server: %s", err.Error()))
+	}
+
+	if err := CloseDB(); err != nil {
+		logger.Error(0, "", fmt.Sprintf("close db: %s", err.Error()))
+	}
+
+	logger.Info(0, "", "shutdown complete")
+	logger.Sync()
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Message struct {
+	Text       string
+	StatusType Status
+
+	// Title is the status, or the kind of notice such as FLAPPING.
+	Title     string
+	Monitor   *Monitor
+	Reason    string
+	CheckedAt time.Time
+}
+
+func (m Message) Status() string {
+	return m.StatusType.String()
+}
+
+func (m Message) MonitorName() string {
+	if m.Monitor == nil {
+		return ""
+	}
+	return m.Monitor.Name
+}
+
+func (m Message) URL() string {
+	if m.Monitor == nil {
+		return ""
+	}
+	return m.Monitor.URL.String()
+}
+
+// MarshalJSON encodes the message as the payload sent to integrations.
+func (m Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Title     string    `json:"title"`
+		Status    string    `json:"status"`
+		Monitor   string    `json:"monitor"`
+		URL       string    `json:"url"`
+		Reason    string    `json:"reason"`
+		CheckedAt time.Time `json:"checked_at"`
+		Text      string    `json:"text"`
+	}{
+		Title:     m.Title,
+		Status:    m.Status(),
+		Monitor:   m.MonitorName(),
+		URL:       m.URL(),
+		Reason:    m.Reason,
+		CheckedAt: m.CheckedAt,
+		Text:      m.Text,
+	})
+}
@@ -3,36 +3,309 @@ package main
 import (
 	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	MonitorTypeHTTP = "http"
+	MonitorTypeTCP  = "tcp"
+	MonitorTypeDNS  = "dns"
+	MonitorTypePush = "push"
+	MonitorTypeGRPC = "grpc"
+)
+
+const (
+	defaultInterval    = 1 * time.Minute
+	defaultTimeout     = 15 * time.Second
+	defaultBodyMaxSize = 1 << 20
+	defaultRetryDelay  = 1 * time.Second
+	defaultFlapWindow  = 1 * time.Hour
 )
 
 type Monitor struct {
-	ID     int64  `json:"id" toml:"-" db:"id"`
-	Name   string `json:"name" toml:"name" db:"name"`
-	Method string `json:"method" toml:"method" db:"method"`
-	URL    URL    `json:"url" toml:"url" db:"url"`
-	Follow bool   `json:"follow" toml:"follow" db:"follow"`
+	ID               int64          `json:"id" toml:"-" db:"id"`
+	Name             string         `json:"name" toml:"name" db:"name"`
+	Type             string         `json:"type" toml:"type" db:"type"`
+	Method           string         `json:"method" toml:"method" db:"method"`
+	URL              URL            `json:"url" toml:"url" db:"url"`
+	Follow           bool           `json:"follow" toml:"follow" db:"follow"`
+	Interval         Duration       `json:"interval" toml:"interval" db:"interval"`
+	Timeout          Duration       `json:"timeout" toml:"timeout" db:"timeout"`
+	DisableKeepAlive bool           `json:"disable_keep_alive" toml:"disable_keep_alive" db:"disable_keep_alive"`
+	MaxIdleConns     int            `json:"max_idle_conns" toml:"max_idle_conns" db:"max_idle_conns"`
+	ExpectedStatus   ExpectedStatus `json:"expected_status" toml:"expected_status" db:"expected_status"`
+	BodyContains     string         `json:"body_contains" toml:"body_contains" db:"body_contains"`
+	BodyNotContains  string         `json:"body_not_contains" toml:"body_not_contains" db:"body_not_contains"`
+	BodyRegexp       string         `json:"body_regexp" toml:"body_regexp" db:"body_regexp"`
+	BodyMaxSize      int64          `json:"body_max_size" toml:"body_max_size" db:"body_max_size"`
+	Asserts          Assertions     `json:"assert" toml:"assert" db:"asserts"`
+	Headers          StringMap      `json:"-" toml:"headers" db:"headers"`
+	Query            StringMap      `json:"-" toml:"query" db:"query"`
+	Body             string         `json:"-" toml:"body" db:"body"`
+	BodyFile         string         `json:"-" toml:"body_file" db:"-"`
+	ContentType      string         `json:"content_type" toml:"content_type" db:"content_type"`
+	Auth             *Auth          `json:"auth,omitempty" toml:"auth" db:"-"`
+	CertExpiryDays   Days           `json:"cert_expiry_days" toml:"cert_expiry_days" db:"cert_expiry_days"`
+	CertNotAfter     *time.Time     `json:"cert_not_after,omitempty" toml:"-" db:"cert_not_after"`
+	CertDaysLeft     *int           `json:"cert_days_left,omitempty" toml:"-" db:"-"`
+	Payload          string         `json:"payload" toml:"payload" db:"payload"`
+	ExpectBanner     string         `json:"expect_banner" toml:"expect_banner" db:"expect_banner"`
+	RecordType       string         `json:"record_type" toml:"record_type" db:"record_type"`
+	ExpectedAnswers  StringList     `json:"expected_answers" toml:"expected_answers" db:"expected_answers"`
+	PushToken        string         `json:"-" toml:"push_token" db:"push_token"`
+	Grace            Duration       `json:"grace" toml:"grace" db:"grace"`
+	GRPCService      string         `json:"grpc_service" toml:"grpc_service" db:"grpc_service"`
+	WarnLatency      Duration       `json:"warn_latency" toml:"warn_latency" db:"warn_latency"`
+	CritLatency      Duration       `json:"crit_latency" toml:"crit_latency" db:"crit_latency"`
+	FailThreshold    int            `json:"fail_threshold" toml:"fail_threshold" db:"fail_threshold"`
+	RecoverThreshold int            `json:"recover_threshold" toml:"recover_threshold" db:"recover_threshold"`
+	Retries          int            `json:"retries" toml:"retries" db:"retries"`
+	RetryDelay       Duration       `json:"retry_delay" toml:"retry_delay" db:"retry_delay"`
+	FlapThreshold    int            `json:"flap_threshold" toml:"flap_threshold" db:"flap_threshold"`
+	FlapWindow       Duration       `json:"flap_window" toml:"flap_window" db:"flap_window"`
+	ArchivedAt       *time.Time     `json:"archived_at,omitempty" toml:"-" db:"archived_at"`
+	State            *MonitorState  `json:"state,omitempty" toml:"-" db:"-"`
+}
+
+func (m *Monitor) hasBodyAssertion() bool {
+	return m.BodyContains != "" || m.BodyNotContains != "" || m.BodyRegexp != "" || len(m.Asserts) > 0
+}
+
+func (m *Monitor) bodyMaxSize() int64 {
+	if m.BodyMaxSize > 0 {
+		return m.BodyMaxSize
+	}
+	return defaultBodyMaxSize
+}
+
+func (m *Monitor) certExpiryDays() Days {
+	if len(m.CertExpiryDays) > 0 {
+		return m.CertExpiryDays
+	}
+	return defaultCertExpiryDays
+}
+
+// certExpiryThreshold returns the smallest threshold the given days left
+// has dropped below, or 0 if it is above all of them.
+func (m *Monitor) certExpiryThreshold(days int) int {
+	threshold := 0
+	for _, t := range m.certExpiryDays() {
+		if days < t && (threshold == 0 || t < threshold) {
+			threshold = t
+		}
+	}
+	return threshold
+}
+
+func (m *Monitor) recordType() string {
+	if m.RecordType == "" {
+		return "A"
+	}
+	return strings.ToUpper(m.RecordType)
+}
+
+func (m *Monitor) dnsName() string {
+	return strings.TrimPrefix(m.URL.Path, "/")
+}
+
+// latencyStatus returns the status a successful check is degraded to when it
+// took at least crit_latency or warn_latency.
+func (m *Monitor) latencyStatus(latency time.Duration) Status {
+	switch {
+	case m.CritLatency > 0 && latency >= time.Duration(m.CritLatency):
+		return Critical
+	case m.WarnLatency > 0 && latency >= time.Duration(m.WarnLatency):
+		return Warning
+	default:
+		return OK
+	}
+}
+
+// threshold returns the number of consecutive checks with the given outcome
+// needed to change to it.
+func (m *Monitor) threshold(status Status) int {
+	n := m.FailThreshold
+	if status == OK {
+		n = m.RecoverThreshold
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+func (m *Monitor) retryDelay() time.Duration {
+	if m.RetryDelay > 0 {
+		return time.Duration(m.RetryDelay)
+	}
+	return defaultRetryDelay
+}
+
+func (m *Monitor) flapWindow() time.Duration {
+	if m.FlapWindow > 0 {
+		return time.Duration(m.FlapWindow)
+	}
+	return defaultFlapWindow
+}
+
+// MonitorState is the state of a running monitor. It is not persisted and is
+// only filled in for API responses.
+type MonitorState struct {
+	Status        string `json:"status"`
+	PendingStatus string `json:"pending_status,omitempty"`
+	PendingCount  int    `json:"pending_count"`
+	Flapping      bool   `json:"flapping"`
+}
+
+// MonitorDiff summarizes the changes made by InitSyncMonitor.
+type MonitorDiff struct {
+	Added    []string
+	Updated  []MonitorChange
+	Restored []string
+	Archived []string
+}
+
+// MonitorChange lists the columns of a monitor changed by the configuration.
+type MonitorChange struct {
+	Name   string
+	Fields []string
+}
+
+func (d *MonitorDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Updated) == 0 && len(d.Restored) == 0 && len(d.Archived) == 0
+}
+
+func (d *MonitorDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+
+	var parts []string
+	if len(d.Added) > 0 {
+		parts = append(parts, fmt.Sprintf("added %q", d.Added))
+	}
+	for _, c := range d.Updated {
+		parts = append(parts, fmt.Sprintf("updated %q (%s)", c.Name, strings.Join(c.Fields, ", ")))
+	}
+	if len(d.Restored) > 0 {
+		parts = append(parts, fmt.Sprintf("restored %q", d.Restored))
+	}
+	if len(d.Archived) > 0 {
+		parts = append(parts, fmt.Sprintf("archived %q", d.Archived))
+	}
+	return strings.Join(parts, ", ")
 }
 
-func InitSyncMonitor(monitors []*Monitor) ([]*Monitor, error) {
+// changedColumns returns the configuration columns that differ between two
+// monitors, compared as they are stored in the database.
+func changedColumns(a, b *Monitor) []string {
+	var changed []string
+	av, bv := monitorConfigValues(a), monitorConfigValues(b)
+	for i, column := range monitorConfigColumns {
+		if !reflect.DeepEqual(driverValue(av[i]), driverValue(bv[i])) {
+			changed = append(changed, column)
+		}
+	}
+	return changed
+}
+
+func driverValue(v interface{}) interface{} {
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return v
+		}
+		return dv
+	}
+	return v
+}
+
+// InitSyncMonitor reconciles the stored monitors with the configuration.
+// Monitors are added, updated to match the configuration, or archived when
+// they are removed from it. Archived monitors keep their history and are
+// restored if they are configured again. The changes are made in one
+// transaction, so nothing is changed if any of them fails.
+func InitSyncMonitor(monitors []*Monitor) ([]*Monitor, *MonitorDiff, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	diff := &MonitorDiff{}
 	var notFound []*Monitor
 
+	configured := make(map[string]bool)
 	for _, m := range monitors {
-		_, err := GetMonitorByName(m.Name)
+		configured[m.Name] = true
+
+		stored, err := getMonitorByName(tx, m.Name)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				notFound = append(notFound, m)
-			} else {
-				return nil, err
+				diff.Added = append(diff.Added, m.Name)
+				continue
 			}
+			return nil, nil, err
+		}
+
+		changed := changedColumns(stored, m)
+		if len(changed) == 0 && stored.ArchivedAt == nil {
+			continue
+		}
+		if err := updateMonitor(tx, stored.ID, m); err != nil {
+			return nil, nil, err
+		}
+		if stored.ArchivedAt != nil {
+			diff.Restored = append(diff.Restored, m.Name)
+		}
+		if len(changed) > 0 {
+			diff.Updated = append(diff.Updated, MonitorChange{Name: m.Name, Fields: changed})
+		}
+	}
+
+	if err := createMonitors(tx, notFound); err != nil {
+		return nil, nil, err
+	}
+
+	stored, err := getAllMonitors(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	for _, s := range stored {
+		if configured[s.Name] {
+			continue
 		}
+		if err := archiveMonitor(tx, s.ID, now); err != nil {
+			return nil, nil, err
+		}
+		diff.Archived = append(diff.Archived, s.Name)
+	}
+
+	synced, err := getAllMonitors(tx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := CreateMonitors(notFound); err != nil {
-		return nil, err
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	// Credentials are not persisted, so carry them over from the config.
+	for _, s := range synced {
+		for _, m := range monitors {
+			if s.Name == m.Name {
+				s.Auth = m.Auth
+			}
+		}
 	}
 
-	return GetAllMonitors()
+	return synced, diff, nil
 }
 
 type URL url.URL
@@ -74,3 +347,70 @@ func (u *URL) UnmarshalText(text []byte) error {
 func (u *URL) String() string {
 	return (*url.URL)(u).String()
 }
+
+type StringList []string
+
+// https://golang.org/pkg/database/sql/driver/#Value
+func (l StringList) Value() (driver.Value, error) {
+	return jsonValue(l, len(l) == 0)
+}
+
+// https://golang.org/pkg/database/sql/#Scanner
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l, "string list")
+}
+
+type StringMap map[string]string
+
+// https://golang.org/pkg/database/sql/driver/#Value
+func (m StringMap) Value() (driver.Value, error) {
+	return jsonValue(m, len(m) == 0)
+}
+
+// https://golang.org/pkg/database/sql/#Scanner
+func (m *StringMap) Scan(value interface{}) error {
+	return scanJSON(value, m, "string map")
+}
+
+type Duration time.Duration
+
+// https://golang.org/pkg/database/sql/driver/#Value
+func (d *Duration) Value() (driver.Value, error) {
+	return driver.Value(d.String()), nil
+}
+
+// https://golang.org/pkg/database/sql/#Scanner
+func (d *Duration) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported duration value: %v", value)
+	}
+
+	return d.UnmarshalText([]byte(s))
+}
+
+// https://golang.org/pkg/encoding/#TextMarshaler
+func (d *Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// https://golang.org/pkg/encoding/#TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+func (d *Duration) String() string {
+	return time.Duration(*d).String()
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitSyncMonitor(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	auth := &Auth{Type: AuthBasic, Username: "user", Password: "pass"}
+	monitors := []*Monitor{
+		{
+			Name:     "GET /monitor/get",
+			Type:     MonitorTypeHTTP,
+			Method:   "GET",
+			URL:      parseURL(t, "http://example.com/monitor/get"),
+			Interval: Duration(defaultInterval),
+			Timeout:  Duration(defaultTimeout),
+			Auth:     auth,
+		},
+		{
+			Name:     "GET /monitor/follow",
+			Type:     MonitorTypeHTTP,
+			Method:   "HEAD",
+			URL:      parseURL(t, "http://example.com/monitor/follow/v2"),
+			Follow:   true,
+			Interval: Duration(defaultInterval),
+			Timeout:  Duration(defaultTimeout),
+		},
+		{
+			Name:     "GET /monitor/new",
+			Type:     MonitorTypeHTTP,
+			Method:   "GET",
+			URL:      parseURL(t, "http://example.com/monitor/new"),
+			Interval: Duration(defaultInterval),
+			Timeout:  Duration(defaultTimeout),
+		},
+	}
+
+	got, diff, err := InitSyncMonitor(monitors)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &MonitorDiff{
+		Added:    []string{"GET /monitor/new"},
+		Updated:  []MonitorChange{{Name: "GET /monitor/follow", Fields: []string{"method", "url"}}},
+		Archived: []string{"POST /monitor/post"},
+	}, diff)
+	assert.Len(t, got, 3)
+	assert.Equal(t, "GET /monitor/get", got[0].Name)
+	assert.Equal(t, auth, got[0].Auth)
+	assert.Equal(t, "GET /monitor/follow", got[1].Name)
+	assert.Equal(t, "HEAD", got[1].Method)
+	assert.Equal(t, "http://example.com/monitor/follow/v2", got[1].URL.String())
+	assert.Equal(t, "GET /monitor/new", got[2].Name)
+	assert.Nil(t, got[2].Auth)
+
+	// The history of an archived monitor is kept.
+	results, err := GetResultsByMonitorID(2)
+	assert.Nil(t, err)
+	assert.Len(t, results, 5)
+
+	// Syncing the same configuration again changes nothing.
+	_, diff, err = InitSyncMonitor(monitors)
+
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+}
+
+func TestInitSyncMonitor_restore(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	post := &Monitor{
+		Name:     "POST /monitor/post",
+		Type:     MonitorTypeHTTP,
+		Method:   "POST",
+		URL:      parseURL(t, "http://example.com/monitor/post"),
+		Interval: Duration(defaultInterval),
+		Timeout:  Duration(defaultTimeout),
+	}
+
+	_, diff, err := InitSyncMonitor(nil)
+	assert.Nil(t, err)
+	assert.Len(t, diff.Archived, 3)
+
+	got, diff, err := InitSyncMonitor([]*Monitor{post})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &MonitorDiff{Restored: []string{"POST /monitor/post"}}, diff)
+	assert.Len(t, got, 1)
+	assert.Equal(t, int64(2), got[0].ID)
+	assert.Nil(t, got[0].ArchivedAt)
+}
+
+func TestInitSyncMonitor_rollback(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	newMonitor := func(name, url string) *Monitor {
+		return &Monitor{
+			Name:     name,
+			Type:     MonitorTypeHTTP,
+			Method:   "GET",
+			URL:      parseURL(t, url),
+			Interval: Duration(defaultInterval),
+			Timeout:  Duration(defaultTimeout),
+		}
+	}
+
+	// The duplicate insert fails after the update and before the archives.
+	_, _, err := InitSyncMonitor([]*Monitor{
+		newMonitor("GET /monitor/get", "http://example.com/monitor/get/v2"),
+		newMonitor("dup", "http://example.com/dup"),
+		newMonitor("dup", "http://example.com/dup"),
+	})
+	assert.NotNil(t, err)
+
+	stored, err := GetAllMonitors()
+	assert.Nil(t, err)
+	assert.Len(t, stored, 3)
+	assert.Equal(t, "http://example.com/monitor/get", stored[0].URL.String())
+
+	// The database is not left locked.
+	err = CreateResult(&Result{CheckedAt: time.Now().UTC(), Status: "OK", Reason: "200 OK", MonitorID: stored[0].ID})
+	assert.Nil(t, err)
+}
+
+func TestMonitorDiff_String(t *testing.T) {
+	cases := []struct {
+		name string
+		diff *MonitorDiff
+		want string
+	}{
+		{name: "empty", diff: &MonitorDiff{}, want: "no changes"},
+		{
+			name: "all",
+			diff: &MonitorDiff{
+				Added:    []string{"a", "b"},
+				Updated:  []MonitorChange{{Name: "c", Fields: []string{"url", "follow"}}},
+				Restored: []string{"d"},
+				Archived: []string{"e"},
+			},
+			want: `added ["a" "b"], updated "c" (url, follow), restored ["d"], archived ["e"]`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, c.diff.String())
+		})
+	}
+}
+
+func TestMonitor_certExpiryThreshold(t *testing.T) {
+	cases := []struct {
+		name       string
+		thresholds Days
+		days       int
+		want       int
+	}{
+		{name: "default above all", thresholds: nil, days: 45, want: 0},
+		{name: "default below 30", thresholds: nil, days: 29, want: 30},
+		{name: "default below 14", thresholds: nil, days: 10, want: 14},
+		{name: "default below 3", thresholds: nil, days: 0, want: 3},
+		{name: "at threshold", thresholds: nil, days: 14, want: 30},
+		{name: "custom", thresholds: Days{7, 60}, days: 20, want: 60},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &Monitor{CertExpiryDays: c.thresholds}
+			assert.Equal(t, c.want, m.certExpiryThreshold(c.days))
+		})
+	}
+}
+
+func TestMonitor_latencyStatus(t *testing.T) {
+	cases := []struct {
+		name    string
+		warn    time.Duration
+		crit    time.Duration
+		latency time.Duration
+		want    Status
+	}{
+		{name: "no thresholds", latency: time.Minute, want: OK},
+		{name: "below warn", warn: time.Second, crit: 3 * time.Second, latency: 500 * time.Millisecond, want: OK},
+		{name: "at warn", warn: time.Second, crit: 3 * time.Second, latency: time.Second, want: Warning},
+		{name: "above crit", warn: time.Second, crit: 3 * time.Second, latency: 4 * time.Second, want: Critical},
+		{name: "crit only", crit: 3 * time.Second, latency: 2 * time.Second, want: OK},
+		{name: "warn only", warn: time.Second, latency: time.Minute, want: Warning},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &Monitor{WarnLatency: Duration(c.warn), CritLatency: Duration(c.crit)}
+			assert.Equal(t, c.want, m.latencyStatus(c.latency))
+		})
+	}
+}
+
+func TestMonitor_threshold(t *testing.T) {
+	cases := []struct {
+		name    string
+		monitor *Monitor
+		status  Status
+		want    int
+	}{
+		{name: "default fail", monitor: &Monitor{}, status: Critical, want: 1},
+		{name: "default recover", monitor: &Monitor{}, status: OK, want: 1},
+		{name: "fail", monitor: &Monitor{FailThreshold: 3, RecoverThreshold: 2}, status: Critical, want: 3},
+		{name: "warning", monitor: &Monitor{FailThreshold: 3, RecoverThreshold: 2}, status: Warning, want: 3},
+		{name: "recover", monitor: &Monitor{FailThreshold: 3, RecoverThreshold: 2}, status: OK, want: 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, c.monitor.threshold(c.status))
+		})
+	}
+}
@@ -1,11 +1,59 @@
 package main
 
-import "github.com/slack-go/slack"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/slack-go/slack"
+)
 
 type Notifier interface {
 	Notify(Message) error
 }
 
+// NewNotifiers creates the notifiers configured in n.
+func NewNotifiers(n *Notification) ([]Notifier, error) {
+	var notifiers []Notifier
+	if n == nil {
+		return notifiers, nil
+	}
+
+	if n.Slack != nil {
+		notifiers = append(notifiers, NewSlackNotifier(n.Slack.Token, n.Slack.Channel))
+	}
+	for _, w := range n.Webhooks {
+		notifier, err := NewWebhookNotifier(w)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if n.Email != nil {
+		notifier, err := NewEmailNotifier(n.Email)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if n.PagerDuty != nil {
+		notifiers = append(notifiers, NewPagerDutyNotifier(n.PagerDuty))
+	}
+	if n.Teams != nil {
+		notifiers = append(notifiers, NewTeamsNotifier(n.Teams.URL))
+	}
+	if n.Discord != nil {
+		notifiers = append(notifiers, NewDiscordNotifier(n.Discord.URL))
+	}
+	for _, e := range n.Execs {
+		notifiers = append(notifiers, NewExecNotifier(e))
+	}
+
+	return notifiers, nil
+}
+
 type SlackNotifier struct {
 	Channel string
 	Client  *slack.Client
@@ -35,12 +83,41 @@ func (s *SlackNotifier) Notify(msg Message) error {
 }
 
 func (s *SlackNotifier) color(status Status) string {
+	return statusColor(status)
+}
+
+// statusColor returns the color name used to render status. Notifiers for
+// services with their own palettes translate it.
+func statusColor(status Status) string {
 	switch {
 	case status == OK:
 		return "good"
 	case status == Critical:
 		return "danger"
+	case status == Warning:
+		return "warning"
 	default:
 		return "#808080"
 	}
 }
+
+// postJSON posts v encoded as JSON to url and returns an error unless the
+// response is 2xx.
+func postJSON(client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return nil
+}
@@ -14,6 +14,7 @@ func TestSlackNotifier_color(t *testing.T) {
 		{status: OK, want: "good"},
 		{status: Critical, want: "danger"},
 		{status: Unknown, want: "#808080"},
+		{status: Warning, want: "warning"},
 	}
 
 	s := SlackNotifier{}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+	pagerDutyTrigger = "trigger"
+	pagerDutyResolve = "resolve"
+)
+
+// PagerDutyNotifier sends PagerDuty Events API v2 events. A CRITICAL status
+// triggers an incident and a recovery resolves it. Both use a dedup key
+// derived from the monitor ID, so that the incident follows the monitor.
+type PagerDutyNotifier struct {
+	URL        string
+	RoutingKey string
+
+	client *http.Client
+}
+
+func NewPagerDutyNotifier(p *PagerDuty) *PagerDutyNotifier {
+	n := &PagerDutyNotifier{
+		URL:        p.URL,
+		RoutingKey: p.RoutingKey,
+		client:     &http.Client{Timeout: webhookTimeout},
+	}
+	if n.URL == "" {
+		n.URL = defaultPagerDutyURL
+	}
+	return n
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Client      string            `json:"client,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     time.Time         `json:"timestamp"`
+	CustomDetails map[string]string `json:"custom_details"`
+}
+
+func (p *PagerDutyNotifier) Notify(msg Message) error {
+	event := p.event(msg)
+	if event == nil {
+		return nil
+	}
+
+	if err := postJSON(p.client, p.URL, event); err != nil {
+		return fmt.Errorf("pagerduty: %s %s: %s", event.EventAction, event.DedupKey, err)
+	}
+	return nil
+}
+
+// event returns the event for msg, or nil if msg does not open or close an
+// incident. Status changes and the notice sent when a monitor stops flapping
+// are passed on; flapping notices and warnings are not.
+func (p *PagerDutyNotifier) event(msg Message) *pagerDutyEvent {
+	if msg.Monitor == nil {
+		return nil
+	}
+	if msg.Title != msg.Status() && msg.Title != "STABLE" {
+		return nil
+	}
+
+	event := &pagerDutyEvent{
+		RoutingKey: p.RoutingKey,
+		DedupKey:   fmt.Sprintf("heartilly-monitor-%d", msg.Monitor.ID),
+	}
+	switch msg.StatusType {
+	case Critical:
+		event.EventAction = pagerDutyTrigger
+		event.Client = "heartilly"
+		event.Payload = &pagerDutyPayload{
+			Summary:   fmt.Sprintf("%s: %s - %s", msg.Status(), msg.MonitorName(), msg.Reason),
+			Source:    msg.URL(),
+			Severity:  "critical",
+			Timestamp: msg.CheckedAt,
+			CustomDetails: map[string]string{
+				"monitor": msg.MonitorName(),
+				"url":     msg.URL(),
+				"reason":  msg.Reason,
+			},
+		}
+	case OK:
+		event.EventAction = pagerDutyResolve
+	default:
+		return nil
+	}
+	return event
+}
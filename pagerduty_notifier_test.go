@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerDutyNotifier_Notify(t *testing.T) {
+	cases := []struct {
+		name   string
+		title  string
+		status Status
+
+		wantBody string
+	}{
+		{
+			name:   "trigger",
+			title:  "CRITICAL",
+			status: Critical,
+			wantBody: `{
+				"routing_key": "key",
+				"event_action": "trigger",
+				"dedup_key": "heartilly-monitor-1",
+				"client": "heartilly",
+				"payload": {
+					"summary": "CRITICAL: example.com check - 500 Internal Server Error",
+					"source": "https://example.com/check",
+					"severity": "critical",
+					"timestamp": "2006-01-02T15:04:05Z",
+					"custom_details": {
+						"monitor": "example.com check",
+						"url": "https://example.com/check",
+						"reason": "500 Internal Server Error"
+					}
+				}
+			}`,
+		},
+		{
+			name:   "resolve",
+			title:  "OK",
+			status: OK,
+			wantBody: `{
+				"routing_key": "key",
+				"event_action": "resolve",
+				"dedup_key": "heartilly-monitor-1"
+			}`,
+		},
+		{
+			name:   "resolve when stable",
+			title:  "STABLE",
+			status: OK,
+			wantBody: `{
+				"routing_key": "key",
+				"event_action": "resolve",
+				"dedup_key": "heartilly-monitor-1"
+			}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts, reqCh := newCaptureServer(http.StatusAccepted)
+			defer ts.Close()
+
+			n := NewPagerDutyNotifier(&PagerDuty{RoutingKey: "key", URL: ts.URL})
+
+			msg := newTestMessage(t)
+			msg.Title = c.title
+			msg.StatusType = c.status
+			err := n.Notify(msg)
+			assert.Nil(t, err)
+
+			req := <-reqCh
+			assert.JSONEq(t, c.wantBody, req.body)
+			assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+		})
+	}
+}
+
+func TestPagerDutyNotifier_Notify_ignored(t *testing.T) {
+	cases := []struct {
+		name   string
+		title  string
+		status Status
+	}{
+		{name: "warning", title: "WARNING", status: Warning},
+		{name: "unknown", title: "UNKNOWN", status: Unknown},
+		{name: "flapping", title: "FLAPPING", status: Warning},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts, reqCh := newCaptureServer(http.StatusAccepted)
+			defer ts.Close()
+
+			n := NewPagerDutyNotifier(&PagerDuty{RoutingKey: "key", URL: ts.URL})
+
+			msg := newTestMessage(t)
+			msg.Title = c.title
+			msg.StatusType = c.status
+			err := n.Notify(msg)
+			assert.Nil(t, err)
+			assert.Len(t, reqCh, 0)
+		})
+	}
+}
+
+func TestPagerDutyNotifier_Notify_error(t *testing.T) {
+	ts, _ := newCaptureServer(http.StatusBadRequest)
+	defer ts.Close()
+
+	n := NewPagerDutyNotifier(&PagerDuty{RoutingKey: "key", URL: ts.URL})
+
+	err := n.Notify(newTestMessage(t))
+	assert.NotNil(t, err)
+}
+
+func TestNewPagerDutyNotifier_defaultURL(t *testing.T) {
+	n := NewPagerDutyNotifier(&PagerDuty{RoutingKey: "key"})
+
+	assert.Equal(t, defaultPagerDutyURL, n.URL)
+}
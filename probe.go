@@ -1,45 +1,246 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 )
 
-type Probe struct {
+type Probe interface {
+	Check(ctx context.Context) (bool, string, error)
+}
+
+// certificateProbe is implemented by probes that can report the expiry of
+// the certificate chain presented by the endpoint.
+type certificateProbe interface {
+	// CertNotAfter returns the earliest expiry in the chain seen by the
+	// last check, or zero if the endpoint is not served over TLS.
+	CertNotAfter() time.Time
+}
+
+// responseProbe is implemented by probes that can report the response
+// received by the last check.
+type responseProbe interface {
+	// Response returns the status code and the number of body bytes read
+	// by the last check, or zeros if no response was received.
+	Response() (int, int64)
+}
+
+// triggeredProbe is implemented by probes that can ask to be checked
+// before the next interval.
+type triggeredProbe interface {
+	Trigger() <-chan struct{}
+}
+
+// waitingProbe is implemented by probes that may have nothing to report yet.
+// The worker skips checks while Waiting returns true, so that the status
+// restored at start is kept.
+type waitingProbe interface {
+	Waiting() bool
+}
+
+func NewProbe(m *Monitor) (Probe, error) {
+	switch m.Type {
+	case MonitorTypeTCP:
+		return NewTCPProbe(m), nil
+	case MonitorTypeDNS:
+		return NewDNSProbe(m), nil
+	case MonitorTypePush:
+		return NewPushProbe(m), nil
+	case MonitorTypeGRPC:
+		return NewGRPCProbe(m)
+	default:
+		return NewHTTPProbe(m)
+	}
+}
+
+type HTTPProbe struct {
 	Monitor *Monitor
+
+	certNotAfter time.Time
+	statusCode   int
+	bodyBytes    int64
+
+	client *http.Client
 }
 
-func (p *Probe) Check(ctx context.Context) (bool, string, error) {
-	client := http.DefaultClient
-	if p.Monitor.Follow {
-		client.CheckRedirect = nil
-	} else {
+func NewHTTPProbe(m *Monitor) (*HTTPProbe, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = m.DisableKeepAlive
+	if m.MaxIdleConns > 0 {
+		transport.MaxIdleConns = m.MaxIdleConns
+		transport.MaxIdleConnsPerHost = m.MaxIdleConns
+	}
+	if m.Auth != nil {
+		tlsConfig, err := m.Auth.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil {
+			transport.TLSClientConfig = tlsConfig
+		}
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(m.Timeout),
+	}
+	if !m.Follow {
 		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}
 	}
 
-	client.Timeout = 15 * time.Second
+	return &HTTPProbe{
+		Monitor: m,
+		client:  client,
+	}, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, p.Monitor.Method, p.Monitor.URL.String(), nil)
+func (p *HTTPProbe) Check(ctx context.Context) (bool, string, error) {
+	req, err := p.newRequest(ctx)
 	if err != nil {
 		return false, "error", err
 	}
 
-	resp, err := client.Do(req)
+	p.certNotAfter = time.Time{}
+	p.statusCode = 0
+	p.bodyBytes = 0
+
+	resp, err := p.client.Do(req)
 	if err != nil {
 		if err, ok := err.(net.Error); ok && err.Timeout() {
 			return false, "timeout", nil
 		}
+		if reason, ok := certErrorReason(err); ok {
+			return false, reason, nil
+		}
 		return false, "error", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
+	p.statusCode = resp.StatusCode
+	if resp.TLS != nil {
+		p.certNotAfter = certNotAfter(resp.TLS.PeerCertificates)
+	}
+
+	if !p.Monitor.ExpectedStatus.Match(resp.StatusCode) {
+		if len(p.Monitor.ExpectedStatus) > 0 {
+			return false, fmt.Sprintf("%s (expected %s)", resp.Status, p.Monitor.ExpectedStatus.String()), nil
+		}
 		return false, resp.Status, nil
 	}
 
+	if p.Monitor.hasBodyAssertion() {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, p.Monitor.bodyMaxSize()))
+		p.bodyBytes = int64(len(body))
+		if err != nil {
+			if err, ok := err.(net.Error); ok && err.Timeout() {
+				return false, "timeout", nil
+			}
+			return false, "error", err
+		}
+
+		ok, reason, err := p.checkBody(body)
+		if err != nil {
+			return false, "error", err
+		}
+		if !ok {
+			return false, reason, nil
+		}
+	} else {
+		// Read the body anyway to record its size. This also lets the
+		// connection be reused.
+		p.bodyBytes, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, p.Monitor.bodyMaxSize()))
+	}
+
 	return true, resp.Status, nil
 }
+
+func (p *HTTPProbe) CertNotAfter() time.Time {
+	return p.certNotAfter
+}
+
+func (p *HTTPProbe) Response() (int, int64) {
+	return p.statusCode, p.bodyBytes
+}
+
+func (p *HTTPProbe) newRequest(ctx context.Context) (*http.Request, error) {
+	u := url.URL(p.Monitor.URL)
+	if len(p.Monitor.Query) > 0 {
+		q := u.Query()
+		for k, v := range p.Monitor.Query {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var body io.Reader
+	if p.Monitor.Body != "" {
+		body = strings.NewReader(p.Monitor.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, p.Monitor.Method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range p.Monitor.Headers {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	if p.Monitor.ContentType != "" {
+		req.Header.Set("Content-Type", p.Monitor.ContentType)
+	}
+	if p.Monitor.Auth != nil {
+		p.Monitor.Auth.SetRequest(req)
+	}
+
+	return req, nil
+}
+
+func (p *HTTPProbe) checkBody(body []byte) (bool, string, error) {
+	if s := p.Monitor.BodyContains; s != "" && !bytes.Contains(body, []byte(s)) {
+		return false, fmt.Sprintf("body missing %q", s), nil
+	}
+
+	if s := p.Monitor.BodyNotContains; s != "" && bytes.Contains(body, []byte(s)) {
+		return false, fmt.Sprintf("body contains %q", s), nil
+	}
+
+	if s := p.Monitor.BodyRegexp; s != "" {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return false, "", err
+		}
+		if !re.Match(body) {
+			return false, fmt.Sprintf("body does not match %q", s), nil
+		}
+	}
+
+	if len(p.Monitor.Asserts) > 0 {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return false, "body is not valid JSON", nil
+		}
+
+		for _, a := range p.Monitor.Asserts {
+			if ok, reason := a.Evaluate(doc); !ok {
+				return false, reason, nil
+			}
+		}
+	}
+
+	return true, "", nil
+}
@@ -2,15 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func newTestServer() *httptest.Server {
+	ts := httptest.NewServer(newTestHandler())
+	return ts
+}
+
+func newTestHandler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -18,21 +32,58 @@ func newTestServer() *httptest.Server {
 	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	})
+	mux.HandleFunc("/unauthorized", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/body", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "status: ok\nversion: 1.2.3\n")
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"status":"ok","db":{"healthy":false}}`)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "method=%s\n", r.Method)
+		fmt.Fprintf(w, "host=%s\n", r.Host)
+		fmt.Fprintf(w, "query=%s\n", r.URL.RawQuery)
+		fmt.Fprintf(w, "content-type=%s\n", r.Header.Get("Content-Type"))
+		fmt.Fprintf(w, "x-api-key=%s\n", r.Header.Get("X-Api-Key"))
+		fmt.Fprintf(w, "body=%s\n", body)
+	})
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); ok && user == "user" && pass == "pass" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Header.Get("Authorization") == "Bearer token" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/error_page", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "<html><body>Internal Error</body></html>")
+	})
 	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Location", "/ok")
 		w.WriteHeader(http.StatusMovedPermanently)
 	})
 
-	ts := httptest.NewServer(mux)
-	return ts
+	return mux
 }
 
-func TestProbe_Check(t *testing.T) {
+func TestHTTPProbe_Check(t *testing.T) {
 	cases := []struct {
-		name   string
-		method string
-		path   string
-		follow bool
+		name           string
+		method         string
+		path           string
+		follow         bool
+		expectedStatus ExpectedStatus
 
 		wantResult bool
 		wantReason string
@@ -78,6 +129,39 @@ func TestProbe_Check(t *testing.T) {
 			wantReason: "200 OK",
 			wantErr:    nil,
 		},
+		{
+			name:           "redirect not expected",
+			method:         "GET",
+			path:           "/redirect",
+			follow:         false,
+			expectedStatus: ExpectedStatus{"200", "204"},
+
+			wantResult: false,
+			wantReason: "301 Moved Permanently (expected 200, 204)",
+			wantErr:    nil,
+		},
+		{
+			name:           "expected unauthorized",
+			method:         "GET",
+			path:           "/unauthorized",
+			follow:         false,
+			expectedStatus: ExpectedStatus{"401"},
+
+			wantResult: true,
+			wantReason: "401 Unauthorized",
+			wantErr:    nil,
+		},
+		{
+			name:           "expected class",
+			method:         "GET",
+			path:           "/redirect",
+			follow:         false,
+			expectedStatus: ExpectedStatus{"3xx"},
+
+			wantResult: true,
+			wantReason: "301 Moved Permanently",
+			wantErr:    nil,
+		},
 	}
 
 	ts := newTestServer()
@@ -86,11 +170,15 @@ func TestProbe_Check(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			monitor := &Monitor{
-				Method: c.method,
-				URL:    parseURL(t, fmt.Sprintf("%s%s", ts.URL, c.path)),
-				Follow: c.follow,
+				Method:         c.method,
+				URL:            parseURL(t, fmt.Sprintf("%s%s", ts.URL, c.path)),
+				Follow:         c.follow,
+				ExpectedStatus: c.expectedStatus,
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
 			}
-			probe := &Probe{Monitor: monitor}
 			result, reason, err := probe.Check(context.TODO())
 
 			assert.Equal(t, c.wantResult, result)
@@ -99,3 +187,619 @@ func TestProbe_Check(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPProbe_Check_body(t *testing.T) {
+	cases := []struct {
+		name            string
+		path            string
+		bodyContains    string
+		bodyNotContains string
+		bodyRegexp      string
+		bodyMaxSize     int64
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name:         "contains",
+			path:         "/body",
+			bodyContains: "status: ok",
+
+			wantResult: true,
+			wantReason: "200 OK",
+		},
+		{
+			name:         "missing",
+			path:         "/error_page",
+			bodyContains: "ok",
+
+			wantResult: false,
+			wantReason: `body missing "ok"`,
+		},
+		{
+			name:            "not contains",
+			path:            "/body",
+			bodyNotContains: "Error",
+
+			wantResult: true,
+			wantReason: "200 OK",
+		},
+		{
+			name:            "contains unwanted",
+			path:            "/error_page",
+			bodyNotContains: "Error",
+
+			wantResult: false,
+			wantReason: `body contains "Error"`,
+		},
+		{
+			name:       "regexp match",
+			path:       "/body",
+			bodyRegexp: `version: \d+\.\d+\.\d+`,
+
+			wantResult: true,
+			wantReason: "200 OK",
+		},
+		{
+			name:       "regexp mismatch",
+			path:       "/error_page",
+			bodyRegexp: `version: \d+`,
+
+			wantResult: false,
+			wantReason: `body does not match "version: \\d+"`,
+		},
+		{
+			name:         "beyond max size",
+			path:         "/body",
+			bodyContains: "version",
+			bodyMaxSize:  10,
+
+			wantResult: false,
+			wantReason: `body missing "version"`,
+		},
+	}
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Method:          "GET",
+				URL:             parseURL(t, fmt.Sprintf("%s%s", ts.URL, c.path)),
+				BodyContains:    c.bodyContains,
+				BodyNotContains: c.bodyNotContains,
+				BodyRegexp:      c.bodyRegexp,
+				BodyMaxSize:     c.bodyMaxSize,
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestHTTPProbe_Check_assert(t *testing.T) {
+	cases := []struct {
+		name    string
+		path    string
+		asserts Assertions
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name: "passing",
+			path: "/health",
+			asserts: Assertions{
+				{Path: "status", Operator: OperatorEquals, Value: "ok"},
+				{Path: "db.healthy", Operator: OperatorExists},
+			},
+
+			wantResult: true,
+			wantReason: "200 OK",
+		},
+		{
+			name: "failing",
+			path: "/health",
+			asserts: Assertions{
+				{Path: "status", Operator: OperatorEquals, Value: "ok"},
+				{Path: "db.healthy", Operator: OperatorEquals, Value: true},
+			},
+
+			wantResult: false,
+			wantReason: `json "db.healthy" is false, expected true`,
+		},
+		{
+			name: "not json",
+			path: "/error_page",
+			asserts: Assertions{
+				{Path: "status", Operator: OperatorExists},
+			},
+
+			wantResult: false,
+			wantReason: "body is not valid JSON",
+		},
+	}
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Method:  "GET",
+				URL:     parseURL(t, fmt.Sprintf("%s%s", ts.URL, c.path)),
+				Asserts: c.asserts,
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestHTTPProbe_Check_request(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	monitor := &Monitor{
+		Method:      "POST",
+		URL:         parseURL(t, fmt.Sprintf("%s/echo?a=1", ts.URL)),
+		Headers:     StringMap{"X-Api-Key": "secret", "Host": "api.example.com"},
+		Query:       StringMap{"b": "2"},
+		Body:        `{"ping":true}`,
+		ContentType: "application/json",
+	}
+	probe, err := NewHTTPProbe(monitor)
+	if err != nil {
+		t.Fatal("create probe failed:", err)
+	}
+
+	cases := []string{
+		"method=POST\n",
+		"host=api.example.com\n",
+		"query=a=1&b=2\n",
+		"content-type=application/json\n",
+		"x-api-key=secret\n",
+		`body={"ping":true}` + "\n",
+	}
+	for _, want := range cases {
+		monitor.BodyContains = want
+		result, reason, err := probe.Check(context.TODO())
+
+		assert.True(t, result, reason)
+		assert.Nil(t, err)
+	}
+}
+
+func TestHTTPProbe_Check_auth(t *testing.T) {
+	cases := []struct {
+		name string
+		auth *Auth
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name: "no auth",
+			auth: nil,
+
+			wantResult: false,
+			wantReason: "401 Unauthorized",
+		},
+		{
+			name: "basic",
+			auth: &Auth{Type: AuthBasic, Username: "user", Password: "pass"},
+
+			wantResult: true,
+			wantReason: "200 OK",
+		},
+		{
+			name: "basic wrong password",
+			auth: &Auth{Type: AuthBasic, Username: "user", Password: "wrong"},
+
+			wantResult: false,
+			wantReason: "401 Unauthorized",
+		},
+		{
+			name: "bearer",
+			auth: &Auth{Type: AuthBearer, Token: "token"},
+
+			wantResult: true,
+			wantReason: "200 OK",
+		},
+	}
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Method: "GET",
+				URL:    parseURL(t, fmt.Sprintf("%s/auth", ts.URL)),
+				Auth:   c.auth,
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestHTTPProbe_Check_mTLS(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create tempdir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	notAfter := time.Now().Add(24 * time.Hour)
+	ca := newTestCert(t, dir, "ca", nil, notAfter)
+	server := newTestCert(t, dir, "server", ca, notAfter)
+	client := newTestCert(t, dir, "client", ca, notAfter)
+
+	serverCert, err := tls.LoadX509KeyPair(server.CertFile, server.KeyFile)
+	if err != nil {
+		t.Fatal("load server certificate failed:", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+
+	ts := httptest.NewUnstartedServer(newTestHandler())
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	cases := []struct {
+		name string
+		auth *Auth
+
+		wantResult bool
+		wantErr    bool
+	}{
+		{
+			name: "client certificate",
+			auth: &Auth{ClientCert: client.CertFile, ClientKey: client.KeyFile, CAFile: ca.CertFile},
+
+			wantResult: true,
+			wantErr:    false,
+		},
+		{
+			name: "without client certificate",
+			auth: &Auth{CAFile: ca.CertFile},
+
+			wantResult: false,
+			wantErr:    true,
+		},
+		{
+			name: "unknown ca",
+			auth: &Auth{ClientCert: client.CertFile, ClientKey: client.KeyFile},
+
+			wantResult: false,
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Method: "GET",
+				URL:    parseURL(t, fmt.Sprintf("%s/ok", ts.URL)),
+				Auth:   c.auth,
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, _, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantErr, err != nil)
+		})
+	}
+}
+
+func TestHTTPProbe_Check_certificate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create tempdir failed:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, dir, "ca", nil, time.Now().Add(365*24*time.Hour))
+	valid := newTestCert(t, dir, "valid", ca, time.Now().Add(10*24*time.Hour))
+	expired := newTestCert(t, dir, "expired", ca, time.Now().Add(-1*time.Hour))
+
+	newTLSServer := func(c *testCert) *httptest.Server {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			t.Fatal("load certificate failed:", err)
+		}
+		ts := httptest.NewUnstartedServer(newTestHandler())
+		ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+		ts.StartTLS()
+		return ts
+	}
+
+	validServer := newTLSServer(valid)
+	defer validServer.Close()
+	expiredServer := newTLSServer(expired)
+	defer expiredServer.Close()
+
+	cases := []struct {
+		name string
+		url  string
+
+		wantResult       bool
+		wantReason       string
+		wantCertNotAfter time.Time
+	}{
+		{
+			name: "valid",
+			url:  fmt.Sprintf("%s/ok", validServer.URL),
+
+			wantResult:       true,
+			wantReason:       "200 OK",
+			wantCertNotAfter: valid.Cert.NotAfter,
+		},
+		{
+			name: "expired",
+			url:  fmt.Sprintf("%s/ok", expiredServer.URL),
+
+			wantResult: false,
+			wantReason: "certificate expired",
+		},
+		{
+			name: "hostname mismatch",
+			url:  strings.Replace(fmt.Sprintf("%s/ok", validServer.URL), "127.0.0.1", "localhost", 1),
+
+			wantResult: false,
+			wantReason: "certificate hostname mismatch: localhost",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Method: "GET",
+				URL:    parseURL(t, c.url),
+				Auth:   &Auth{CAFile: ca.CertFile},
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+			assert.True(t, c.wantCertNotAfter.Equal(probe.CertNotAfter()))
+		})
+	}
+}
+
+func TestHTTPProbe_Response(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	cases := []struct {
+		name         string
+		path         string
+		bodyContains string
+
+		wantCode  int
+		wantBytes int64
+	}{
+		{name: "body", path: "/body", wantCode: http.StatusOK, wantBytes: 26},
+		{name: "body with assertion", path: "/body", bodyContains: "ok", wantCode: http.StatusOK, wantBytes: 26},
+		{name: "error", path: "/error", wantCode: http.StatusInternalServerError, wantBytes: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Method:       "GET",
+				URL:          parseURL(t, ts.URL+c.path),
+				BodyContains: c.bodyContains,
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			_, _, err = probe.Check(context.TODO())
+			assert.Nil(t, err)
+
+			code, bytes := probe.Response()
+			assert.Equal(t, c.wantCode, code)
+			assert.Equal(t, c.wantBytes, bytes)
+		})
+	}
+}
+
+func TestNewHTTPProbe_invalidCertificate(t *testing.T) {
+	monitor := &Monitor{
+		Method: "GET",
+		URL:    parseURL(t, "https://example.com/"),
+		Auth:   &Auth{ClientCert: "/nonexistent/cert.pem", ClientKey: "/nonexistent/key.pem"},
+	}
+	_, err := NewHTTPProbe(monitor)
+
+	assert.NotNil(t, err)
+}
+
+func TestNewProbe(t *testing.T) {
+	cases := []struct {
+		name    string
+		monitor *Monitor
+		want    Probe
+	}{
+		{name: "default", monitor: &Monitor{}, want: &HTTPProbe{}},
+		{name: "http", monitor: &Monitor{Type: MonitorTypeHTTP}, want: &HTTPProbe{}},
+		{name: "tcp", monitor: &Monitor{Type: MonitorTypeTCP}, want: &TCPProbe{}},
+		{name: "dns", monitor: &Monitor{Type: MonitorTypeDNS}, want: &DNSProbe{}},
+		{name: "push", monitor: &Monitor{Type: MonitorTypePush}, want: &PushProbe{}},
+		{name: "grpc", monitor: &Monitor{Type: MonitorTypeGRPC}, want: &GRPCProbe{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := NewProbe(c.monitor)
+
+			assert.Nil(t, err)
+			assert.IsType(t, c.want, got)
+		})
+	}
+}
+
+func TestNewHTTPProbe(t *testing.T) {
+	cases := []struct {
+		name    string
+		monitor *Monitor
+
+		wantDisableKeepAlives bool
+		wantMaxIdleConns      int
+		wantFollow            bool
+	}{
+		{
+			name:    "default",
+			monitor: &Monitor{Timeout: Duration(15 * time.Second)},
+
+			wantDisableKeepAlives: false,
+			wantMaxIdleConns:      http.DefaultTransport.(*http.Transport).MaxIdleConns,
+			wantFollow:            false,
+		},
+		{
+			name: "connection reuse settings",
+			monitor: &Monitor{
+				Timeout:          Duration(15 * time.Second),
+				Follow:           true,
+				DisableKeepAlive: true,
+				MaxIdleConns:     4,
+			},
+
+			wantDisableKeepAlives: true,
+			wantMaxIdleConns:      4,
+			wantFollow:            true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			probe, err := NewHTTPProbe(c.monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+
+			assert.NotSame(t, http.DefaultClient, probe.client)
+			assert.Equal(t, time.Duration(c.monitor.Timeout), probe.client.Timeout)
+			assert.Equal(t, c.wantFollow, probe.client.CheckRedirect == nil)
+
+			transport := probe.client.Transport.(*http.Transport)
+			assert.NotSame(t, http.DefaultTransport, transport)
+			assert.Equal(t, c.wantDisableKeepAlives, transport.DisableKeepAlives)
+			assert.Equal(t, c.wantMaxIdleConns, transport.MaxIdleConns)
+		})
+	}
+}
+
+func TestHTTPProbe_Check_keepAlive(t *testing.T) {
+	cases := []struct {
+		name             string
+		disableKeepAlive bool
+		wantConns        int64
+	}{
+		{name: "keep-alive", disableKeepAlive: false, wantConns: 1},
+		{name: "no keep-alive", disableKeepAlive: true, wantConns: 3},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var conns int64
+			ts := httptest.NewUnstartedServer(newTestHandler())
+			ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt64(&conns, 1)
+				}
+			}
+			ts.Start()
+			defer ts.Close()
+
+			monitor := &Monitor{
+				Method:           "GET",
+				URL:              parseURL(t, fmt.Sprintf("%s/ok", ts.URL)),
+				DisableKeepAlive: c.disableKeepAlive,
+			}
+			probe, err := NewHTTPProbe(monitor)
+			if err != nil {
+				t.Fatal("create probe failed:", err)
+			}
+			for i := 0; i < 3; i++ {
+				if _, _, err := probe.Check(context.TODO()); err != nil {
+					t.Fatal("check failed:", err)
+				}
+			}
+
+			assert.Equal(t, c.wantConns, atomic.LoadInt64(&conns))
+		})
+	}
+}
+
+func TestHTTPProbe_Check_concurrent(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		follow := i%2 == 0
+		monitor := &Monitor{
+			Method:  "GET",
+			URL:     parseURL(t, fmt.Sprintf("%s/redirect", ts.URL)),
+			Follow:  follow,
+			Timeout: Duration(time.Duration(i+1) * time.Second),
+		}
+		probe, err := NewHTTPProbe(monitor)
+		if err != nil {
+			t.Fatal("create probe failed:", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				_, reason, err := probe.Check(context.TODO())
+				assert.Nil(t, err)
+				if follow {
+					assert.Equal(t, "200 OK", reason)
+				} else {
+					assert.Equal(t, "301 Moved Permanently", reason)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
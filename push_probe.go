@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// pushProbes maps push tokens to the probes waiting for their pings.
+var pushProbes = newPushRegistry()
+
+type pushRegistry struct {
+	mu     sync.RWMutex
+	probes map[string]*PushProbe
+}
+
+func newPushRegistry() *pushRegistry {
+	return &pushRegistry{
+		probes: make(map[string]*PushProbe),
+	}
+}
+
+func (r *pushRegistry) Register(p *PushProbe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.probes[p.Monitor.PushToken] = p
+}
+
+func (r *pushRegistry) Unregister(p *PushProbe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.probes[p.Monitor.PushToken] == p {
+		delete(r.probes, p.Monitor.PushToken)
+	}
+}
+
+func (r *pushRegistry) Get(token string) (*PushProbe, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.probes[token]
+	return p, ok
+}
+
+// PushProbe is a dead man's switch. Instead of polling, it waits for the
+// monitored job to ping it, and fails when no ping arrives within the
+// monitor interval plus grace period, or when the job reports a failure.
+type PushProbe struct {
+	Monitor *Monitor
+
+	mu       sync.Mutex
+	since    time.Time
+	lastPing time.Time
+	failed   bool
+	message  string
+
+	triggerCh chan struct{}
+	// timer triggers a check as soon as the expected ping is overdue,
+	// instead of at the next interval.
+	timer *time.Timer
+}
+
+func NewPushProbe(m *Monitor) *PushProbe {
+	p := &PushProbe{
+		Monitor:   m,
+		since:     time.Now(),
+		triggerCh: make(chan struct{}, 1),
+	}
+	p.timer = time.AfterFunc(p.deadline(), p.trigger)
+	return p
+}
+
+// Ping records a report from the job and asks the worker to check now.
+func (p *PushProbe) Ping(ok bool, message string) {
+	p.mu.Lock()
+	p.lastPing = time.Now()
+	p.failed = !ok
+	p.message = message
+	p.timer.Reset(p.deadline())
+	p.mu.Unlock()
+
+	p.trigger()
+}
+
+// Stop stops scheduling checks for overdue pings.
+func (p *PushProbe) Stop() {
+	p.timer.Stop()
+}
+
+func (p *PushProbe) trigger() {
+	select {
+	case p.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// deadline returns how long a ping may be waited for.
+func (p *PushProbe) deadline() time.Duration {
+	return time.Duration(p.Monitor.Interval) + time.Duration(p.Monitor.Grace)
+}
+
+// Trigger returns a channel that receives when the probe should be checked
+// without waiting for the next interval.
+func (p *PushProbe) Trigger() <-chan struct{} {
+	return p.triggerCh
+}
+
+// Waiting reports whether no ping has arrived since the probe was created
+// and the first one is not overdue yet. Pings are not persisted, so the
+// probe cannot tell until then whether the job is still running.
+func (p *PushProbe) Waiting() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.lastPing.IsZero() && time.Since(p.since) < p.deadline()
+}
+
+func (p *PushProbe) Check(ctx context.Context) (bool, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failed {
+		if p.message == "" {
+			return false, "job reported failure", nil
+		}
+		return false, p.message, nil
+	}
+
+	deadline := p.deadline()
+	if p.lastPing.IsZero() {
+		if time.Since(p.since) >= deadline {
+			return false, fmt.Sprintf("no ping received in %s", deadline), nil
+		}
+		return true, "waiting for first ping", nil
+	}
+
+	if time.Since(p.lastPing) >= deadline {
+		return false, fmt.Sprintf("no ping since %s", p.lastPing.UTC().Format(time.RFC3339)), nil
+	}
+
+	if p.message == "" {
+		return true, "ping received", nil
+	}
+	return true, p.message, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushProbe_Check(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		since    time.Time
+		lastPing time.Time
+		failed   bool
+		message  string
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name:  "waiting for first ping",
+			since: now.Add(-30 * time.Second),
+
+			wantResult: true,
+			wantReason: "waiting for first ping",
+		},
+		{
+			name:  "first ping overdue",
+			since: now.Add(-2 * time.Minute),
+
+			wantResult: false,
+			wantReason: "no ping received in 1m10s",
+		},
+		{
+			name:     "ping within period",
+			since:    now.Add(-2 * time.Minute),
+			lastPing: now.Add(-65 * time.Second),
+
+			wantResult: true,
+			wantReason: "ping received",
+		},
+		{
+			name:     "ping overdue",
+			since:    now.Add(-5 * time.Minute),
+			lastPing: now.Add(-2 * time.Minute),
+
+			wantResult: false,
+			wantReason: "no ping since " + now.Add(-2*time.Minute).UTC().Format(time.RFC3339),
+		},
+		{
+			name:     "reported failure",
+			since:    now.Add(-2 * time.Minute),
+			lastPing: now,
+			failed:   true,
+			message:  "backup failed: disk full",
+
+			wantResult: false,
+			wantReason: "backup failed: disk full",
+		},
+		{
+			name:     "reported failure without message",
+			since:    now.Add(-2 * time.Minute),
+			lastPing: now,
+			failed:   true,
+
+			wantResult: false,
+			wantReason: "job reported failure",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Type:      MonitorTypePush,
+				PushToken: "token",
+				Interval:  Duration(1 * time.Minute),
+				Grace:     Duration(10 * time.Second),
+			}
+			probe := NewPushProbe(monitor)
+			probe.since = c.since
+			probe.lastPing = c.lastPing
+			probe.failed = c.failed
+			probe.message = c.message
+
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestPushProbe_Waiting(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		since    time.Time
+		lastPing time.Time
+
+		want bool
+	}{
+		{name: "waiting for first ping", since: now.Add(-30 * time.Second), want: true},
+		{name: "first ping overdue", since: now.Add(-2 * time.Minute), want: false},
+		{name: "ping received", since: now.Add(-30 * time.Second), lastPing: now, want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Type:      MonitorTypePush,
+				PushToken: "token",
+				Interval:  Duration(1 * time.Minute),
+				Grace:     Duration(10 * time.Second),
+			}
+			probe := NewPushProbe(monitor)
+			defer probe.Stop()
+			probe.since = c.since
+			probe.lastPing = c.lastPing
+
+			assert.Equal(t, c.want, probe.Waiting())
+		})
+	}
+}
+
+func TestPushProbe_Ping(t *testing.T) {
+	monitor := &Monitor{
+		Type:      MonitorTypePush,
+		PushToken: "token",
+		Interval:  Duration(1 * time.Minute),
+	}
+	probe := NewPushProbe(monitor)
+
+	probe.Ping(false, "exit status 1")
+	probe.Ping(false, "exit status 2")
+
+	select {
+	case <-probe.Trigger():
+	default:
+		t.Fatal("ping did not trigger a check")
+	}
+
+	result, reason, err := probe.Check(context.TODO())
+	assert.False(t, result)
+	assert.Equal(t, "exit status 2", reason)
+	assert.Nil(t, err)
+
+	probe.Ping(true, "")
+	<-probe.Trigger()
+
+	result, reason, err = probe.Check(context.TODO())
+	assert.True(t, result)
+	assert.Equal(t, "ping received", reason)
+	assert.Nil(t, err)
+}
+
+func TestPushRegistry(t *testing.T) {
+	registry := newPushRegistry()
+
+	old := NewPushProbe(&Monitor{PushToken: "token"})
+	registry.Register(old)
+
+	got, ok := registry.Get("token")
+	assert.True(t, ok)
+	assert.Same(t, old, got)
+
+	replaced := NewPushProbe(&Monitor{PushToken: "token"})
+	registry.Register(replaced)
+
+	// unregistering a replaced probe keeps its successor
+	registry.Unregister(old)
+	got, ok = registry.Get("token")
+	assert.True(t, ok)
+	assert.Same(t, replaced, got)
+
+	registry.Unregister(replaced)
+	_, ok = registry.Get("token")
+	assert.False(t, ok)
+}
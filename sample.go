@@ -0,0 +1,24 @@
+package main
+
+import "time"
+
+// Error classes recorded with a sample that is not OK.
+const (
+	ErrorClassTimeout = "timeout"
+	ErrorClassFailure = "failure"
+	ErrorClassLatency = "latency"
+	ErrorClassError   = "error"
+)
+
+// Sample is the outcome of a single check. Unlike Result, which is only
+// written when the status changes, a sample is recorded for every check.
+type Sample struct {
+	ID         int64     `json:"id" db:"id"`
+	CheckedAt  time.Time `json:"checked_at" db:"checked_at"`
+	Status     string    `json:"status" db:"status"`
+	Code       int       `json:"code" db:"code"`
+	LatencyMS  int64     `json:"latency_ms" db:"latency_ms"`
+	Bytes      int64     `json:"bytes" db:"bytes"`
+	ErrorClass string    `json:"error_class" db:"error_class"`
+	MonitorID  int64     `json:"-" db:"monitor_id"`
+}
@@ -0,0 +1,62 @@
+package main
+
+import "time"
+
+const (
+	defaultSampleBatchSize     = 100
+	defaultSampleFlushInterval = 5 * time.Second
+)
+
+// SampleWriter collects samples from workers and writes them in batches, so
+// that frequent checks on many monitors result in few transactions.
+type SampleWriter struct {
+	BatchSize     int
+	FlushInterval time.Duration
+
+	SampleCh <-chan *Sample
+	ErrCh    chan<- error
+}
+
+// Run writes a batch when it is full or when the flush interval elapses. It
+// writes the remaining samples and returns when SampleCh is closed.
+func (sw *SampleWriter) Run() {
+	batchSize := sw.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultSampleBatchSize
+	}
+	flushInterval := sw.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultSampleFlushInterval
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Sample
+	for {
+		select {
+		case s, ok := <-sw.SampleCh:
+			if !ok {
+				sw.flush(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		sw.flush(batch)
+		batch = nil
+	}
+}
+
+func (sw *SampleWriter) flush(batch []*Sample) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := CreateSamples(batch); err != nil {
+		sw.ErrCh <- err
+	}
+}
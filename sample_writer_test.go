@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleWriter_Run(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	sampleCh := make(chan *Sample)
+	errCh := make(chan error, 10)
+	sw := &SampleWriter{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		SampleCh:      sampleCh,
+		ErrCh:         errCh,
+	}
+	done := make(chan struct{})
+	go func() {
+		sw.Run()
+		close(done)
+	}()
+
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		sampleCh <- &Sample{
+			CheckedAt: checkedAt.Add(time.Duration(i) * time.Second),
+			Status:    "OK",
+			Code:      200,
+			LatencyMS: int64(i),
+			MonitorID: 1,
+		}
+	}
+
+	// The first two samples fill a batch and are written right away.
+	assert.Eventually(t, func() bool {
+		got, err := GetSamplesByMonitorID(1, checkedAt)
+		return err == nil && len(got) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The rest is written when the channel is closed.
+	close(sampleCh)
+	<-done
+
+	got, err := GetSamplesByMonitorID(1, checkedAt)
+	assert.Nil(t, err)
+	assert.Len(t, got, 3)
+	assert.Len(t, errCh, 0)
+}
+
+func TestSampleWriter_Run_flushInterval(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	sampleCh := make(chan *Sample)
+	errCh := make(chan error, 10)
+	sw := &SampleWriter{
+		BatchSize:     100,
+		FlushInterval: 10 * time.Millisecond,
+		SampleCh:      sampleCh,
+		ErrCh:         errCh,
+	}
+	go sw.Run()
+	defer close(sampleCh)
+
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+	sampleCh <- &Sample{CheckedAt: checkedAt, Status: "OK", MonitorID: 1}
+
+	assert.Eventually(t, func() bool {
+		got, err := GetSamplesByMonitorID(1, checkedAt)
+		return err == nil && len(got) == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -1,11 +1,14 @@
 package main
 
+import "fmt"
+
 type Status int
 
 const (
 	OK Status = iota
 	Critical
 	Unknown
+	Warning
 )
 
 func (s *Status) String() string {
@@ -14,11 +17,29 @@ func (s *Status) String() string {
 		return "OK"
 	case *s == Critical:
 		return "CRITICAL"
+	case *s == Warning:
+		return "WARNING"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// ParseStatus returns the status for the string returned by String.
+func ParseStatus(s string) (Status, error) {
+	switch s {
+	case "OK":
+		return OK, nil
+	case "CRITICAL":
+		return Critical, nil
+	case "WARNING":
+		return Warning, nil
+	case "UNKNOWN":
+		return Unknown, nil
+	default:
+		return Unknown, fmt.Errorf("unknown status %q", s)
+	}
+}
+
 func (s *Status) Recovery() {
 	*s = OK
 }
@@ -27,6 +48,10 @@ func (s *Status) Trigger() {
 	*s = Critical
 }
 
+func (s *Status) Warn() {
+	*s = Warning
+}
+
 func (s *Status) Unknown() {
 	*s = Unknown
 }
@@ -23,6 +23,10 @@ func TestStatus_String(t *testing.T) {
 			status: Unknown,
 			want:   "UNKNOWN",
 		},
+		{
+			status: Warning,
+			want:   "WARNING",
+		},
 	}
 
 	for _, c := range cases {
@@ -33,6 +37,19 @@ func TestStatus_String(t *testing.T) {
 	}
 }
 
+func TestParseStatus(t *testing.T) {
+	for _, want := range []Status{OK, Critical, Unknown, Warning} {
+		t.Run(want.String(), func(t *testing.T) {
+			got, err := ParseStatus(want.String())
+			assert.Nil(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	_, err := ParseStatus("DOWN")
+	assert.NotNil(t, err)
+}
+
 func TestStatus_Recovery(t *testing.T) {
 	for _, s := range []Status{OK, Critical, Unknown} {
 		s.Recovery()
@@ -47,6 +64,13 @@ func TestStatus_Trigger(t *testing.T) {
 	}
 }
 
+func TestStatus_Warn(t *testing.T) {
+	for _, s := range []Status{OK, Critical, Unknown, Warning} {
+		s.Warn()
+		assert.Equal(t, Warning, s)
+	}
+}
+
 func TestStatus_Unknown(t *testing.T) {
 	for _, s := range []Status{OK, Critical, Unknown} {
 		s.Unknown()
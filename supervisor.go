@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Supervisor runs a worker for each monitor and applies configuration changes
+// to the running workers without a restart.
+type Supervisor struct {
+	ConfigFile string
+
+	AlertSender *AlertSender
+	MessageCh   chan<- Message
+	SampleCh    chan<- *Sample
+
+	Logger *Logger
+
+	// mu serializes Apply and Shutdown.
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running map[int64]*runningWorker
+	closed  bool
+}
+
+type runningWorker struct {
+	worker *Worker
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// Reload reads the configuration file again and applies it.
+func (s *Supervisor) Reload() (*MonitorDiff, error) {
+	config, err := LoadConfig(s.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Apply(config)
+}
+
+// Apply syncs the monitors in config to the database, replaces the notifiers,
+// and starts, stops or restarts workers to match. A restarted worker keeps the
+// status of the worker it replaces. Workers being stopped finish their
+// running check first.
+func (s *Supervisor) Apply(config *Config) (*MonitorDiff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, errors.New("supervisor is shut down")
+	}
+	if s.running == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+		s.running = make(map[int64]*runningWorker)
+	}
+
+	notifiers, err := NewNotifiers(config.Notification)
+	if err != nil {
+		return nil, err
+	}
+
+	monitors, diff, err := InitSyncMonitor(config.Monitors)
+	if err != nil {
+		return nil, err
+	}
+
+	s.AlertSender.SetNotifiers(notifiers)
+
+	var probeErr error
+	var starting []*Worker
+	stopping := make(map[int64]*runningWorker)
+	configured := make(map[int64]bool)
+	for _, m := range monitors {
+		configured[m.ID] = true
+
+		r, ok := s.running[m.ID]
+		if ok && !monitorChanged(r.worker.Monitor, m) {
+			continue
+		}
+
+		p, err := NewProbe(m)
+		if err != nil {
+			probeErr = fmt.Errorf("monitor %q: %s", m.Name, err)
+			s.Logger.Error(int(m.ID), m.URL.String(), probeErr.Error())
+			continue
+		}
+
+		starting = append(starting, &Worker{
+			ID: int(m.ID),
+
+			Monitor: m,
+			Probe:   p,
+
+			MessageCh: s.MessageCh,
+			SampleCh:  s.SampleCh,
+
+			Logger: s.Logger,
+		})
+		if ok {
+			stopping[m.ID] = r
+		}
+	}
+	for id, r := range s.running {
+		if !configured[id] {
+			stopping[id] = r
+		}
+	}
+
+	for _, r := range stopping {
+		close(r.stop)
+	}
+	for id, r := range stopping {
+		<-r.done
+		s.unregister(r.worker)
+		delete(s.running, id)
+	}
+
+	for _, w := range starting {
+		if r, ok := stopping[w.Monitor.ID]; ok {
+			w.Status = r.worker.Status
+			w.certNotAfter = r.worker.certNotAfter
+			w.certAlerted = r.worker.certAlerted
+		} else {
+			w.restoreCertificate()
+			if err := w.restoreStatus(); err != nil {
+				s.Logger.Error(w.ID, w.Monitor.URL.String(), fmt.Sprintf("restore status failed: %s", err.Error()))
+			}
+		}
+		s.start(w)
+	}
+
+	return diff, probeErr
+}
+
+// Shutdown stops all workers and waits for their running checks to finish.
+// When ctx is done first, the remaining checks are cancelled. Apply fails
+// after Shutdown.
+func (s *Supervisor) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for _, r := range s.running {
+		close(r.stop)
+	}
+
+	var err error
+	for id, r := range s.running {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			err = ctx.Err()
+			s.cancel()
+			<-r.done
+		}
+		s.unregister(r.worker)
+		delete(s.running, id)
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	return err
+}
+
+func (s *Supervisor) start(w *Worker) {
+	r := &runningWorker{
+		worker: w,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.running[w.Monitor.ID] = r
+
+	if pp, ok := w.Probe.(*PushProbe); ok {
+		pushProbes.Register(pp)
+	}
+	workers.Register(w)
+
+	go func() {
+		defer close(r.done)
+		w.run(s.ctx, r.stop)
+	}()
+}
+
+func (s *Supervisor) unregister(w *Worker) {
+	workers.Unregister(w)
+	if pp, ok := w.Probe.(*PushProbe); ok {
+		pushProbes.Unregister(pp)
+		pp.Stop()
+	}
+}
+
+// monitorChanged reports whether a running worker has to be restarted to
+// apply the new settings of its monitor.
+func monitorChanged(running, m *Monitor) bool {
+	return len(changedColumns(running, m)) > 0 || !reflect.DeepEqual(running.Auth, m.Auth)
+}
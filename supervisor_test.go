@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupervisor_Apply(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	alertSender := &AlertSender{}
+	supervisor := &Supervisor{
+		AlertSender: alertSender,
+		MessageCh:   make(chan Message, 100),
+		Logger:      newTestLogger(),
+	}
+	defer supervisor.Apply(&Config{})
+
+	newHTTPMonitor := func(timeout time.Duration) *Monitor {
+		return &Monitor{
+			Name:     "GET /monitor/follow",
+			Type:     MonitorTypeHTTP,
+			Method:   "GET",
+			URL:      parseURL(t, ts.URL+"/error"),
+			Interval: Duration(defaultInterval),
+			Timeout:  Duration(timeout),
+		}
+	}
+	newPushMonitor := func(name string) *Monitor {
+		return &Monitor{
+			Name:      name,
+			Type:      MonitorTypePush,
+			Method:    "GET",
+			PushToken: name + "-token",
+			Interval:  Duration(defaultInterval),
+			Timeout:   Duration(defaultTimeout),
+		}
+	}
+
+	// Start workers for every monitor.
+	_, err := supervisor.Apply(&Config{
+		Notification: &Notification{Slack: &Slack{Token: "token", Channel: "#general"}},
+		Monitors:     []*Monitor{newHTTPMonitor(defaultTimeout), newPushMonitor("job")},
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, alertSender.Notifiers, 1)
+	assert.Len(t, supervisor.running, 2)
+	httpWorker, ok := workers.Get(3)
+	assert.True(t, ok)
+	assert.Equal(t, "CRITICAL", httpWorker.State().Status)
+	_, ok = pushProbes.Get("job-token")
+	assert.True(t, ok)
+
+	// The restarted worker keeps its status even if the database has none,
+	// and the certificate warnings already sent.
+	if _, err := db.Exec(`DELETE FROM result WHERE monitor_id = 3`); err != nil {
+		t.Fatal("delete results failed:", err)
+	}
+	httpWorker.certAlerted = 14
+
+	config := &Config{
+		Monitors: []*Monitor{newHTTPMonitor(10 * time.Second), newPushMonitor("nightly")},
+	}
+	diff, err := supervisor.Apply(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nightly"}, diff.Added)
+	assert.Equal(t, []string{"job"}, diff.Archived)
+	assert.Len(t, alertSender.Notifiers, 0)
+	assert.Len(t, supervisor.running, 2)
+	restarted, ok := workers.Get(3)
+	assert.True(t, ok)
+	assert.NotSame(t, httpWorker, restarted)
+	assert.Equal(t, "CRITICAL", restarted.State().Status)
+	assert.Equal(t, 14, restarted.certAlerted)
+	_, ok = pushProbes.Get("job-token")
+	assert.False(t, ok)
+	_, ok = pushProbes.Get("nightly-token")
+	assert.True(t, ok)
+
+	// Unchanged monitors keep running.
+	diff, err = supervisor.Apply(config)
+
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+	unchanged, ok := workers.Get(3)
+	assert.True(t, ok)
+	assert.Same(t, restarted, unchanged)
+}
+
+func TestSupervisor_Shutdown(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	supervisor := &Supervisor{
+		AlertSender: &AlertSender{},
+		MessageCh:   make(chan Message, 100),
+		Logger:      newTestLogger(),
+	}
+	_, err := supervisor.Apply(&Config{
+		Monitors: []*Monitor{
+			{
+				Name:      "job",
+				Type:      MonitorTypePush,
+				Method:    "GET",
+				PushToken: "job-token",
+				Interval:  Duration(defaultInterval),
+				Timeout:   Duration(defaultTimeout),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("apply config failed:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = supervisor.Shutdown(ctx)
+
+	assert.Nil(t, err)
+	assert.Len(t, supervisor.running, 0)
+	_, ok := pushProbes.Get("job-token")
+	assert.False(t, ok)
+
+	_, err = supervisor.Apply(&Config{})
+	assert.NotNil(t, err)
+}
+
+func TestSupervisor_Reload_invalidConfig(t *testing.T) {
+	supervisor := &Supervisor{
+		ConfigFile: "testdata/nonexistent.toml",
+		Logger:     newTestLogger(),
+	}
+	_, err := supervisor.Reload()
+
+	assert.NotNil(t, err)
+}
+
+func TestSupervisor_Reload_missingTLSFile(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("create temporary directory failed", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	config := []byte(`dbfile = "/var/lib/heartilly.db"
+
+[[monitor]]
+name = "GET /monitor/get"
+url = "http://example.com/monitor/get/v2"
+
+[monitor.auth]
+ca_file = "/nonexistent/ca.pem"
+`)
+	filename := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(filename, config, 0644); err != nil {
+		t.Fatal("write file failed", err)
+	}
+
+	supervisor := &Supervisor{
+		ConfigFile:  filename,
+		AlertSender: &AlertSender{},
+		Logger:      newTestLogger(),
+	}
+	_, err = supervisor.Reload()
+	assert.NotNil(t, err)
+
+	// Nothing is synced when the reload fails.
+	stored, err := GetAllMonitors()
+	assert.Nil(t, err)
+	assert.Len(t, stored, 3)
+	assert.Equal(t, "http://example.com/monitor/get", stored[0].URL.String())
+}
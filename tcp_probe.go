@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+type TCPProbe struct {
+	Monitor *Monitor
+}
+
+func NewTCPProbe(m *Monitor) *TCPProbe {
+	return &TCPProbe{
+		Monitor: m,
+	}
+}
+
+func (p *TCPProbe) Check(ctx context.Context) (bool, string, error) {
+	timeout := time.Duration(p.Monitor.Timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	address := p.Monitor.URL.Host
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			return false, "timeout", nil
+		}
+		return false, fmt.Sprintf("connect failed: %s", unwrapOpError(err)), nil
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return false, "error", err
+		}
+	}
+
+	if p.Monitor.Payload != "" {
+		if _, err := io.WriteString(conn, p.Monitor.Payload); err != nil {
+			if err, ok := err.(net.Error); ok && err.Timeout() {
+				return false, "timeout", nil
+			}
+			return false, fmt.Sprintf("send failed: %s", unwrapOpError(err)), nil
+		}
+	}
+
+	if p.Monitor.ExpectBanner != "" {
+		buf := make([]byte, len(p.Monitor.ExpectBanner))
+		n, err := io.ReadFull(conn, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			if err, ok := err.(net.Error); ok && err.Timeout() {
+				return false, "timeout", nil
+			}
+			return false, fmt.Sprintf("receive failed: %s", unwrapOpError(err)), nil
+		}
+		if got := string(buf[:n]); !strings.HasPrefix(got, p.Monitor.ExpectBanner) {
+			return false, fmt.Sprintf("banner mismatch: got %q, expected %q", got, p.Monitor.ExpectBanner), nil
+		}
+	}
+
+	return true, fmt.Sprintf("connected to %s", address), nil
+}
+
+// unwrapOpError drops the "dial tcp ...: connect:" prefixes from network
+// errors so the reason stays short.
+func unwrapOpError(err error) error {
+	if opErr, ok := err.(*net.OpError); ok && opErr.Err != nil {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok && sysErr.Err != nil {
+		err = sysErr.Err
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTCPServer accepts connections and passes each one to handle.
+func newTestTCPServer(t *testing.T, handle func(net.Conn)) net.Listener {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestTCPProbe_Check(t *testing.T) {
+	smtp := newTestTCPServer(t, func(conn net.Conn) {
+		fmt.Fprint(conn, "220 mail.example.com ESMTP\r\n")
+	})
+	defer smtp.Close()
+
+	redis := newTestTCPServer(t, func(conn net.Conn) {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		if line == "PING\r\n" {
+			fmt.Fprint(conn, "+PONG\r\n")
+		} else {
+			fmt.Fprint(conn, "-ERR unknown command\r\n")
+		}
+	})
+	defer redis.Close()
+
+	silent := newTestTCPServer(t, func(conn net.Conn) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	defer silent.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	cases := []struct {
+		name         string
+		address      string
+		payload      string
+		expectBanner string
+
+		wantResult bool
+		wantReason string
+	}{
+		{
+			name:    "connect",
+			address: smtp.Addr().String(),
+
+			wantResult: true,
+			wantReason: fmt.Sprintf("connected to %s", smtp.Addr().String()),
+		},
+		{
+			name:         "banner",
+			address:      smtp.Addr().String(),
+			expectBanner: "220 ",
+
+			wantResult: true,
+			wantReason: fmt.Sprintf("connected to %s", smtp.Addr().String()),
+		},
+		{
+			name:         "banner mismatch",
+			address:      smtp.Addr().String(),
+			expectBanner: "+OK",
+
+			wantResult: false,
+			wantReason: `banner mismatch: got "220", expected "+OK"`,
+		},
+		{
+			name:         "payload",
+			address:      redis.Addr().String(),
+			payload:      "PING\r\n",
+			expectBanner: "+PONG",
+
+			wantResult: true,
+			wantReason: fmt.Sprintf("connected to %s", redis.Addr().String()),
+		},
+		{
+			name:         "timeout waiting for banner",
+			address:      silent.Addr().String(),
+			expectBanner: "220 ",
+
+			wantResult: false,
+			wantReason: "timeout",
+		},
+		{
+			name:    "connection refused",
+			address: closedAddr,
+
+			wantResult: false,
+			wantReason: "connect failed: connection refused",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			monitor := &Monitor{
+				Type:         MonitorTypeTCP,
+				URL:          parseURL(t, fmt.Sprintf("tcp://%s", c.address)),
+				Timeout:      Duration(100 * time.Millisecond),
+				Payload:      c.payload,
+				ExpectBanner: c.expectBanner,
+			}
+			probe := NewTCPProbe(monitor)
+			result, reason, err := probe.Check(context.TODO())
+
+			assert.Equal(t, c.wantResult, result)
+			assert.Equal(t, c.wantReason, reason)
+			assert.Nil(t, err)
+		})
+	}
+}
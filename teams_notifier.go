@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+var teamsColors = map[string]string{
+	"good":    "Good",
+	"danger":  "Attention",
+	"warning": "Warning",
+}
+
+// TeamsNotifier posts an Adaptive Card to a Microsoft Teams incoming webhook.
+type TeamsNotifier struct {
+	URL string
+
+	client *http.Client
+}
+
+func NewTeamsNotifier(url string) *TeamsNotifier {
+	return &TeamsNotifier{
+		URL:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string             `json:"$schema"`
+	Type    string             `json:"type"`
+	Version string             `json:"version"`
+	Body    []teamsCardElement `json:"body"`
+}
+
+type teamsCardElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+func (t *TeamsNotifier) Notify(msg Message) error {
+	if err := postJSON(t.client, t.URL, t.message(msg)); err != nil {
+		return fmt.Errorf("teams: %s", err)
+	}
+	return nil
+}
+
+func (t *TeamsNotifier) message(msg Message) teamsMessage {
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: teamsCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body: []teamsCardElement{
+						{
+							Type:   "TextBlock",
+							Text:   fmt.Sprintf("%s: %s", msg.Title, msg.MonitorName()),
+							Weight: "Bolder",
+							Size:   "Medium",
+							Color:  t.color(msg.StatusType),
+							Wrap:   true,
+						},
+						{
+							Type: "FactSet",
+							Facts: []teamsFact{
+								{Title: "Status", Value: msg.Status()},
+								{Title: "Monitor", Value: msg.MonitorName()},
+								{Title: "URL", Value: msg.URL()},
+								{Title: "Reason", Value: msg.Reason},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (t *TeamsNotifier) color(status Status) string {
+	if c, ok := teamsColors[statusColor(status)]; ok {
+		return c
+	}
+	return "Default"
+}
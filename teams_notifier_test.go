@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamsNotifier_Notify(t *testing.T) {
+	ts, reqCh := newCaptureServer(http.StatusOK)
+	defer ts.Close()
+
+	n := NewTeamsNotifier(ts.URL)
+
+	err := n.Notify(newTestMessage(t))
+	assert.Nil(t, err)
+
+	req := <-reqCh
+	assert.JSONEq(t, `{
+		"type": "message",
+		"attachments": [{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": {
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type": "AdaptiveCard",
+				"version": "1.4",
+				"body": [
+					{
+						"type": "TextBlock",
+						"text": "CRITICAL: example.com check",
+						"weight": "Bolder",
+						"size": "Medium",
+						"color": "Attention",
+						"wrap": true
+					},
+					{
+						"type": "FactSet",
+						"facts": [
+							{"title": "Status", "value": "CRITICAL"},
+							{"title": "Monitor", "value": "example.com check"},
+							{"title": "URL", "value": "https://example.com/check"},
+							{"title": "Reason", "value": "500 Internal Server Error"}
+						]
+					}
+				]
+			}
+		}]
+	}`, req.body)
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+}
+
+func TestTeamsNotifier_Notify_error(t *testing.T) {
+	ts, _ := newCaptureServer(http.StatusBadRequest)
+	defer ts.Close()
+
+	n := NewTeamsNotifier(ts.URL)
+
+	err := n.Notify(newTestMessage(t))
+	assert.NotNil(t, err)
+}
+
+func TestTeamsNotifier_color(t *testing.T) {
+	cases := []struct {
+		status Status
+		want   string
+	}{
+		{status: OK, want: "Good"},
+		{status: Critical, want: "Attention"},
+		{status: Unknown, want: "Default"},
+		{status: Warning, want: "Warning"},
+	}
+
+	n := TeamsNotifier{}
+	for _, c := range cases {
+		t.Run(c.status.String(), func(t *testing.T) {
+			got := n.color(c.status)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+const (
+	webhookTimeout         = 10 * time.Second
+	webhookSignatureHeader = "X-Heartilly-Signature"
+)
+
+// WebhookNotifier posts a JSON body rendered from a template to a URL.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Secret  string
+
+	body   *template.Template
+	client *http.Client
+}
+
+func NewWebhookNotifier(w *Webhook) (*WebhookNotifier, error) {
+	body, err := newWebhookTemplate(w.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookNotifier{
+		URL:     w.URL,
+		Headers: w.Headers,
+		Secret:  w.Secret,
+		body:    body,
+		client:  &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+// newWebhookTemplate parses a body template. Actions are delimited by [[ and ]]
+// because {{ and }} are already expanded when the configuration is loaded.
+// The "json" function encodes a value as JSON, so that strings can be
+// embedded safely. An empty body sends the message as JSON.
+func newWebhookTemplate(body string) (*template.Template, error) {
+	if body == "" {
+		body = "[[ json . ]]"
+	}
+
+	return template.New("body").Delims("[[", "]]").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(body)
+}
+
+func (w *WebhookNotifier) Notify(msg Message) error {
+	var body bytes.Buffer
+	if err := w.body.Execute(&body, msg); err != nil {
+		return fmt.Errorf("webhook %s: render body failed: %s", w.URL, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+w.sign(body.Bytes()))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body keyed with the secret.
+func (w *WebhookNotifier) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMessage(t *testing.T) Message {
+	t.Helper()
+
+	return Message{
+		Text:       "CRITICAL: example.com check\nhttps://example.com/check - 500 Internal Server Error",
+		StatusType: Critical,
+		Title:      "CRITICAL",
+		Monitor: &Monitor{
+			ID:   1,
+			Name: "example.com check",
+			URL:  parseURL(t, "https://example.com/check"),
+		},
+		Reason:    "500 Internal Server Error",
+		CheckedAt: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+}
+
+type capturedRequest struct {
+	header http.Header
+	body   string
+}
+
+func newCaptureServer(code int) (*httptest.Server, <-chan capturedRequest) {
+	ch := make(chan capturedRequest, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ch <- capturedRequest{header: r.Header, body: string(body)}
+		w.WriteHeader(code)
+	}))
+	return ts, ch
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	cases := []struct {
+		name    string
+		webhook Webhook
+
+		wantBody        string
+		wantContentType string
+		wantHeader      string
+	}{
+		{
+			name:    "default body",
+			webhook: Webhook{},
+			wantBody: `{
+				"title": "CRITICAL",
+				"status": "CRITICAL",
+				"monitor": "example.com check",
+				"url": "https://example.com/check",
+				"reason": "500 Internal Server Error",
+				"checked_at": "2006-01-02T15:04:05Z",
+				"text": "CRITICAL: example.com check\nhttps://example.com/check - 500 Internal Server Error"
+			}`,
+			wantContentType: "application/json",
+		},
+		{
+			name: "templated body and headers",
+			webhook: Webhook{
+				Body:    `{"summary": [[ json .Title ]], "service": [[ json .MonitorName ]], "id": [[ .Monitor.ID ]], "detail": [[ json .Reason ]]}`,
+				Headers: map[string]string{"Content-Type": "application/vnd.incident+json", "X-Api-Key": "key"},
+			},
+			wantBody:        `{"summary": "CRITICAL", "service": "example.com check", "id": 1, "detail": "500 Internal Server Error"}`,
+			wantContentType: "application/vnd.incident+json",
+			wantHeader:      "key",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ts, reqCh := newCaptureServer(http.StatusOK)
+			defer ts.Close()
+
+			c.webhook.URL = ts.URL
+			n, err := NewWebhookNotifier(&c.webhook)
+			if err != nil {
+				t.Fatal("create notifier failed:", err)
+			}
+
+			err = n.Notify(newTestMessage(t))
+			assert.Nil(t, err)
+
+			req := <-reqCh
+			assert.JSONEq(t, c.wantBody, req.body)
+			assert.Equal(t, c.wantContentType, req.header.Get("Content-Type"))
+			assert.Equal(t, c.wantHeader, req.header.Get("X-Api-Key"))
+			assert.Empty(t, req.header.Get(webhookSignatureHeader))
+		})
+	}
+}
+
+func TestWebhookNotifier_Notify_signature(t *testing.T) {
+	ts, reqCh := newCaptureServer(http.StatusNoContent)
+	defer ts.Close()
+
+	n, err := NewWebhookNotifier(&Webhook{URL: ts.URL, Secret: "secret"})
+	if err != nil {
+		t.Fatal("create notifier failed:", err)
+	}
+
+	err = n.Notify(newTestMessage(t))
+	assert.Nil(t, err)
+
+	req := <-reqCh
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(req.body))
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), req.header.Get(webhookSignatureHeader))
+}
+
+func TestWebhookNotifier_Notify_error(t *testing.T) {
+	ts, _ := newCaptureServer(http.StatusInternalServerError)
+	defer ts.Close()
+
+	n, err := NewWebhookNotifier(&Webhook{URL: ts.URL})
+	if err != nil {
+		t.Fatal("create notifier failed:", err)
+	}
+
+	err = n.Notify(newTestMessage(t))
+	assert.NotNil(t, err)
+}
+
+func TestNewWebhookNotifier_invalidBody(t *testing.T) {
+	_, err := NewWebhookNotifier(&Webhook{URL: "https://example.com/hook", Body: "[[ .Title"})
+
+	assert.NotNil(t, err)
+}
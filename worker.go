@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// workers maps monitor IDs to their running workers.
+var workers = newWorkerRegistry()
+
+// maxJitter bounds the random delay before the first check of a worker, so
+// that monitors started together are not checked at the same time.
+var maxJitter = 10 * time.Second
+
+type workerRegistry struct {
+	mu      sync.RWMutex
+	workers map[int64]*Worker
+}
+
+func newWorkerRegistry() *workerRegistry {
+	return &workerRegistry{
+		workers: make(map[int64]*Worker),
+	}
+}
+
+func (r *workerRegistry) Register(w *Worker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.workers[w.Monitor.ID] = w
+}
+
+func (r *workerRegistry) Unregister(w *Worker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.workers[w.Monitor.ID] == w {
+		delete(r.workers, w.Monitor.ID)
+	}
+}
+
+func (r *workerRegistry) Get(id int64) (*Worker, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.workers[id]
+	return w, ok
+}
+
+type Worker struct {
+	ID     int
+	Status Status
+
+	Monitor *Monitor
+	Probe   Probe
+
+	MessageCh chan<- Message
+	SampleCh  chan<- *Sample
+
+	Logger *Logger
+
+	// mu guards Status, the pending outcome and the flapping state, which
+	// are read by the API.
+	mu            sync.Mutex
+	pendingStatus Status
+	pendingCount  int
+	changes       []time.Time
+	flapping      bool
+
+	certNotAfter time.Time
+	certAlerted  int
+}
+
+// run checks the monitor every interval until stop is closed. A check in
+// progress is finished first, unless ctx is cancelled.
+func (w *Worker) run(ctx context.Context, stop <-chan struct{}) {
+	// jitter
+	rand.Seed(time.Now().UnixNano())
+	var jitter time.Duration
+	if maxJitter > 0 {
+		jitter = time.Duration(rand.Int63n(int64(maxJitter)))
+	}
+	select {
+	case <-time.After(jitter):
+	case <-stop:
+		return
+	case <-ctx.Done():
+		return
+	}
+
+	w.Logger.Info(w.ID, w.Monitor.URL.String(), "start worker")
+
+	ticker := time.NewTicker(time.Duration(w.Monitor.Interval))
+	defer ticker.Stop()
+
+	var trigger <-chan struct{}
+	if p, ok := w.Probe.(triggeredProbe); ok {
+		trigger = p.Trigger()
+	}
+
+	for {
+		w.Logger.Info(w.ID, w.Monitor.URL.String(), "check")
+
+		w.check(ctx)
+		w.checkCertificate()
+
+		select {
+		case <-ticker.C:
+		case <-trigger:
+		case <-stop:
+			w.Logger.Info(w.ID, w.Monitor.URL.String(), "stop worker")
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// restoreStatus continues from the status persisted by the last run, so that
+// a restart neither repeats nor misses notifications. A monitor that has
+// never changed status starts as OK.
+func (w *Worker) restoreStatus() error {
+	result, err := GetLastResultByMonitorID(w.Monitor.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			w.Status = OK
+			return nil
+		}
+		return err
+	}
+
+	status, err := ParseStatus(result.Status)
+	if err != nil {
+		return err
+	}
+	w.Status = status
+
+	return nil
+}
+
+// restoreCertificate continues from the certificate expiry saved by the last
+// run. The warning for its current threshold is assumed to have been sent
+// then, so that a restart does not repeat it.
+func (w *Worker) restoreCertificate() {
+	if w.Monitor.CertNotAfter == nil {
+		return
+	}
+
+	w.certNotAfter = *w.Monitor.CertNotAfter
+	w.certAlerted = w.Monitor.certExpiryThreshold(daysUntil(w.certNotAfter, time.Now()))
+}
+
+// check runs the probe once, records a sample and changes the status when the
+// outcome differs from the current one. A failed check is retried up to
+// retries times before it counts. A successful check that took longer than
+// the latency thresholds is degraded to WARNING or CRITICAL. Nothing is
+// recorded while the probe is waiting.
+func (w *Worker) check(ctx context.Context) {
+	if p, ok := w.Probe.(waitingProbe); ok && p.Waiting() {
+		return
+	}
+
+	start := time.Now()
+	ok, reason, err := w.Probe.Check(ctx)
+	for i := 0; (!ok || err != nil) && i < w.Monitor.Retries; i++ {
+		select {
+		case <-time.After(w.Monitor.retryDelay()):
+		case <-ctx.Done():
+			return
+		}
+		w.Logger.Info(w.ID, w.Monitor.URL.String(), fmt.Sprintf("retry: %s", reason))
+
+		start = time.Now()
+		ok, reason, err = w.Probe.Check(ctx)
+	}
+	latency := time.Since(start)
+
+	// A check cut short by shutdown says nothing about the monitor.
+	if ctx.Err() != nil {
+		return
+	}
+
+	var status Status
+	var errorClass string
+	switch {
+	case err != nil:
+		status = Unknown
+		errorClass = ErrorClassError
+	case !ok:
+		status = Critical
+		errorClass = ErrorClassFailure
+		if reason == "timeout" {
+			errorClass = ErrorClassTimeout
+		}
+	default:
+		status = w.Monitor.latencyStatus(latency)
+		threshold := w.Monitor.WarnLatency
+		if status == Critical {
+			threshold = w.Monitor.CritLatency
+		}
+		if status != OK {
+			errorClass = ErrorClassLatency
+			reason = fmt.Sprintf("response time %s exceeds %s",
+				latency.Round(time.Millisecond).String(),
+				threshold.String(),
+			)
+		}
+	}
+
+	if w.SampleCh != nil {
+		sample := &Sample{
+			CheckedAt:  start.UTC(),
+			Status:     status.String(),
+			LatencyMS:  latency.Milliseconds(),
+			ErrorClass: errorClass,
+			MonitorID:  w.Monitor.ID,
+		}
+		if p, ok := w.Probe.(responseProbe); ok {
+			sample.Code, sample.Bytes = p.Response()
+		}
+		w.SampleCh <- sample
+	}
+
+	w.mu.Lock()
+	changed := w.confirm(status)
+	started, stopped := w.detectFlapping(time.Now(), changed)
+	w.mu.Unlock()
+
+	if changed {
+		result := &Result{
+			CheckedAt: time.Now().UTC(),
+			Status:    w.Status.String(),
+			Reason:    reason,
+			MonitorID: w.Monitor.ID,
+		}
+		if err := CreateResult(result); err != nil {
+			w.Logger.Error(
+				w.ID,
+				w.Monitor.URL.String(),
+				fmt.Sprintf("save result failed: %s", err.Error()),
+			)
+		}
+
+		if !w.flapping {
+			w.notify(w.Status.String(), status, reason)
+		}
+		w.Logger.Info(
+			w.ID,
+			w.Monitor.URL.String(),
+			fmt.Sprintf("status canged: %s", w.Status.String()),
+		)
+	}
+
+	if started {
+		w.notify("FLAPPING", Warning, fmt.Sprintf(
+			"status changed %d times in %s, notifications are suppressed until it is stable",
+			len(w.changes),
+			w.Monitor.flapWindow().String(),
+		))
+		w.Logger.Info(w.ID, w.Monitor.URL.String(), "flapping started")
+	}
+	if stopped {
+		w.notify("STABLE", w.Status, fmt.Sprintf("status is %s", w.Status.String()))
+		w.Logger.Info(w.ID, w.Monitor.URL.String(), "flapping stopped")
+	}
+}
+
+func (w *Worker) notify(title string, status Status, reason string) {
+	w.MessageCh <- Message{
+		Text: fmt.Sprintf("%s: %s\n%s - %s",
+			title,
+			w.Monitor.Name,
+			w.Monitor.URL.String(),
+			reason,
+		),
+		StatusType: status,
+
+		Title:     title,
+		Monitor:   w.Monitor,
+		Reason:    reason,
+		CheckedAt: time.Now().UTC(),
+	}
+}
+
+// confirm counts consecutive checks whose outcome differs from the current
+// status, and changes the status once fail_threshold (or recover_threshold
+// for OK) is reached. It reports whether the status was changed.
+func (w *Worker) confirm(status Status) bool {
+	if w.Status.Is(status) {
+		w.pendingCount = 0
+		return false
+	}
+
+	// A success interrupts a run of failures and vice versa.
+	if w.pendingCount > 0 && w.pendingStatus.Is(OK) != status.Is(OK) {
+		w.pendingCount = 0
+	}
+	w.pendingStatus = status
+	w.pendingCount++
+	if w.pendingCount < w.Monitor.threshold(status) {
+		return false
+	}
+	w.pendingCount = 0
+
+	switch status {
+	case OK:
+		w.Status.Recovery()
+	case Warning:
+		w.Status.Warn()
+	case Critical:
+		w.Status.Trigger()
+	default:
+		w.Status.Unknown()
+	}
+
+	return true
+}
+
+// detectFlapping keeps the status changes within flap_window and reports
+// whether the monitor has started or stopped flapping. A monitor is flapping
+// once flap_threshold changes fall within the window, and is stable again
+// when no more than half of that remain.
+func (w *Worker) detectFlapping(now time.Time, changed bool) (started, stopped bool) {
+	threshold := w.Monitor.FlapThreshold
+	if threshold == 0 {
+		return false, false
+	}
+
+	if changed {
+		w.changes = append(w.changes, now)
+	}
+	window := w.Monitor.flapWindow()
+	i := 0
+	for i < len(w.changes) && now.Sub(w.changes[i]) > window {
+		i++
+	}
+	w.changes = w.changes[i:]
+
+	switch {
+	case !w.flapping && len(w.changes) >= threshold:
+		w.flapping = true
+		return true, false
+	case w.flapping && len(w.changes) <= threshold/2:
+		w.flapping = false
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// State returns the current status, the outcome waiting for confirmation and
+// whether the monitor is flapping.
+func (w *Worker) State() MonitorState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	state := MonitorState{
+		Status:       w.Status.String(),
+		PendingCount: w.pendingCount,
+		Flapping:     w.flapping,
+	}
+	if w.pendingCount > 0 {
+		state.PendingStatus = w.pendingStatus.String()
+	}
+	return state
+}
+
+// checkCertificate records the certificate expiry seen by the last check and
+// sends a warning each time it drops below a smaller threshold.
+func (w *Worker) checkCertificate() {
+	p, ok := w.Probe.(certificateProbe)
+	if !ok {
+		return
+	}
+
+	notAfter := p.CertNotAfter()
+	if notAfter.IsZero() {
+		return
+	}
+
+	if !notAfter.Equal(w.certNotAfter) {
+		if err := UpdateMonitorCertNotAfter(w.Monitor.ID, notAfter); err != nil {
+			w.Logger.Error(
+				w.ID,
+				w.Monitor.URL.String(),
+				fmt.Sprintf("save certificate expiry failed: %s", err.Error()),
+			)
+		}
+		w.certNotAfter = notAfter
+	}
+
+	days := daysUntil(notAfter, time.Now())
+	threshold := w.Monitor.certExpiryThreshold(days)
+	if threshold == 0 {
+		w.certAlerted = 0
+		return
+	}
+	if w.certAlerted != 0 && threshold >= w.certAlerted {
+		return
+	}
+	w.certAlerted = threshold
+
+	status := Warning
+	w.notify(status.String(), status, fmt.Sprintf("certificate expires in %d days (%s)",
+		days,
+		notAfter.UTC().Format(time.RFC3339),
+	))
+	w.Logger.Info(
+		w.ID,
+		w.Monitor.URL.String(),
+		fmt.Sprintf("certificate expires in %d days", days),
+	)
+}
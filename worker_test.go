@@ -0,0 +1,616 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestLogger() *Logger {
+	return &Logger{baseLogger: zap.NewNop()}
+}
+
+type stubProbe struct {
+	ok     bool
+	reason string
+	err    error
+	delay  time.Duration
+}
+
+func (p *stubProbe) Check(ctx context.Context) (bool, string, error) {
+	time.Sleep(p.delay)
+	return p.ok, p.reason, p.err
+}
+
+func TestWorker_check(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	monitor.WarnLatency = Duration(20 * time.Millisecond)
+	monitor.CritLatency = Duration(100 * time.Millisecond)
+
+	messageCh := make(chan Message, 10)
+	sampleCh := make(chan *Sample, 10)
+	probe := &stubProbe{}
+	worker := &Worker{
+		ID:        1,
+		Status:    OK,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		SampleCh:  sampleCh,
+		Logger:    newTestLogger(),
+	}
+
+	cases := []struct {
+		name           string
+		probe          stubProbe
+		want           Status
+		wantErrorClass string
+		wantMessage    string
+	}{
+		{name: "ok", probe: stubProbe{ok: true, reason: "200 OK"}, want: OK},
+		{name: "slow", probe: stubProbe{ok: true, reason: "200 OK", delay: 50 * time.Millisecond}, want: Warning, wantErrorClass: ErrorClassLatency, wantMessage: "response time"},
+		{name: "still slow", probe: stubProbe{ok: true, reason: "200 OK", delay: 50 * time.Millisecond}, want: Warning, wantErrorClass: ErrorClassLatency},
+		{name: "too slow", probe: stubProbe{ok: true, reason: "200 OK", delay: 150 * time.Millisecond}, want: Critical, wantErrorClass: ErrorClassLatency, wantMessage: "exceeds 100ms"},
+		{name: "failed", probe: stubProbe{ok: false, reason: "500 Internal Server Error"}, want: Critical, wantErrorClass: ErrorClassFailure},
+		{name: "timeout", probe: stubProbe{ok: false, reason: "timeout"}, want: Critical, wantErrorClass: ErrorClassTimeout},
+		{name: "error", probe: stubProbe{err: errors.New("dial failed"), reason: "dial failed"}, want: Unknown, wantErrorClass: ErrorClassError, wantMessage: "dial failed"},
+		{name: "failed after error", probe: stubProbe{ok: false, reason: "500 Internal Server Error"}, want: Critical, wantErrorClass: ErrorClassFailure, wantMessage: "500 Internal Server Error"},
+		{name: "recovered", probe: stubProbe{ok: true, reason: "200 OK"}, want: OK, wantMessage: "200 OK"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			*probe = c.probe
+			worker.check(context.Background())
+
+			assert.Equal(t, c.want, worker.Status)
+
+			sample := <-sampleCh
+			assert.Equal(t, c.want.String(), sample.Status)
+			assert.Equal(t, c.wantErrorClass, sample.ErrorClass)
+			assert.Equal(t, monitor.ID, sample.MonitorID)
+			assert.GreaterOrEqual(t, sample.LatencyMS, c.probe.delay.Milliseconds())
+			if c.wantMessage != "" {
+				msg := <-messageCh
+				assert.Equal(t, c.want, msg.StatusType)
+				assert.Contains(t, msg.Text, c.wantMessage)
+			} else {
+				assert.Len(t, messageCh, 0)
+			}
+		})
+	}
+}
+
+func TestWorker_check_threshold(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	monitor.FailThreshold = 3
+	monitor.RecoverThreshold = 2
+
+	messageCh := make(chan Message, 10)
+	probe := &stubProbe{}
+	worker := &Worker{
+		ID:        1,
+		Status:    OK,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		Logger:    newTestLogger(),
+	}
+
+	cases := []struct {
+		name        string
+		probe       stubProbe
+		want        MonitorState
+		wantMessage bool
+	}{
+		{name: "first failure", probe: stubProbe{reason: "timeout"}, want: MonitorState{Status: "OK", PendingStatus: "CRITICAL", PendingCount: 1}},
+		{name: "interrupted", probe: stubProbe{ok: true, reason: "200 OK"}, want: MonitorState{Status: "OK"}},
+		{name: "failure", probe: stubProbe{reason: "timeout"}, want: MonitorState{Status: "OK", PendingStatus: "CRITICAL", PendingCount: 1}},
+		{name: "unknown counts as failure", probe: stubProbe{reason: "error", err: errors.New("error")}, want: MonitorState{Status: "OK", PendingStatus: "UNKNOWN", PendingCount: 2}},
+		{name: "confirmed", probe: stubProbe{reason: "timeout"}, want: MonitorState{Status: "CRITICAL"}, wantMessage: true},
+		{name: "first success", probe: stubProbe{ok: true, reason: "200 OK"}, want: MonitorState{Status: "CRITICAL", PendingStatus: "OK", PendingCount: 1}},
+		{name: "recovered", probe: stubProbe{ok: true, reason: "200 OK"}, want: MonitorState{Status: "OK"}, wantMessage: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			*probe = c.probe
+			worker.check(context.Background())
+
+			assert.Equal(t, c.want, worker.State())
+			if c.wantMessage {
+				assert.Len(t, messageCh, 1)
+				<-messageCh
+			} else {
+				assert.Len(t, messageCh, 0)
+			}
+		})
+	}
+}
+
+type sequenceProbe struct {
+	results []stubProbe
+	calls   int
+}
+
+func (p *sequenceProbe) Check(ctx context.Context) (bool, string, error) {
+	r := p.results[p.calls]
+	p.calls++
+	return r.ok, r.reason, r.err
+}
+
+func TestWorker_check_retries(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	monitor.Retries = 2
+	monitor.RetryDelay = Duration(time.Millisecond)
+
+	cases := []struct {
+		name      string
+		results   []stubProbe
+		want      Status
+		wantCalls int
+	}{
+		{
+			name:      "no retry on success",
+			results:   []stubProbe{{ok: true}},
+			want:      OK,
+			wantCalls: 1,
+		},
+		{
+			name:      "succeeded on retry",
+			results:   []stubProbe{{reason: "timeout"}, {ok: true}},
+			want:      OK,
+			wantCalls: 2,
+		},
+		{
+			name:      "retries exhausted",
+			results:   []stubProbe{{reason: "timeout"}, {reason: "timeout"}, {reason: "timeout"}},
+			want:      Critical,
+			wantCalls: 3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			probe := &sequenceProbe{results: c.results}
+			worker := &Worker{
+				ID:        1,
+				Status:    OK,
+				Monitor:   monitor,
+				Probe:     probe,
+				MessageCh: make(chan Message, 10),
+				Logger:    newTestLogger(),
+			}
+			worker.check(context.Background())
+
+			assert.Equal(t, c.want, worker.Status)
+			assert.Equal(t, c.wantCalls, probe.calls)
+		})
+	}
+}
+
+func TestWorker_check_cancelled(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+
+	messageCh := make(chan Message, 10)
+	sampleCh := make(chan *Sample, 10)
+	worker := &Worker{
+		ID:        1,
+		Status:    OK,
+		Monitor:   monitor,
+		Probe:     &stubProbe{reason: "connect failed: operation was canceled"},
+		MessageCh: messageCh,
+		SampleCh:  sampleCh,
+		Logger:    newTestLogger(),
+	}
+
+	before, err := GetLastResultByMonitorID(monitor.ID)
+	if err != nil {
+		t.Fatal("get last result failed:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	worker.check(ctx)
+
+	assert.Equal(t, OK, worker.Status)
+	assert.Len(t, sampleCh, 0)
+	assert.Len(t, messageCh, 0)
+	after, err := GetLastResultByMonitorID(monitor.ID)
+	if err != nil {
+		t.Fatal("get last result failed:", err)
+	}
+	assert.Equal(t, before.ID, after.ID)
+}
+
+func TestWorker_check_pushWaiting(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	monitor.Type = MonitorTypePush
+	monitor.PushToken = "token"
+	monitor.Interval = Duration(1 * time.Minute)
+
+	messageCh := make(chan Message, 10)
+	sampleCh := make(chan *Sample, 10)
+	probe := NewPushProbe(monitor)
+	defer probe.Stop()
+	worker := &Worker{
+		ID:        1,
+		Status:    Critical,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		SampleCh:  sampleCh,
+		Logger:    newTestLogger(),
+	}
+
+	// The status restored at start is kept until the first ping.
+	worker.check(context.Background())
+	assert.Equal(t, Critical, worker.Status)
+	assert.Len(t, sampleCh, 0)
+	assert.Len(t, messageCh, 0)
+
+	probe.Ping(true, "")
+	worker.check(context.Background())
+	assert.Equal(t, OK, worker.Status)
+	assert.Len(t, sampleCh, 1)
+	assert.Len(t, messageCh, 1)
+}
+
+func TestWorker_run_pushDeadline(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	defer func(d time.Duration) { maxJitter = d }(maxJitter)
+	maxJitter = 0
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	monitor.Type = MonitorTypePush
+	monitor.PushToken = "token"
+	monitor.Interval = Duration(1 * time.Second)
+	monitor.Grace = Duration(200 * time.Millisecond)
+
+	messageCh := make(chan Message, 10)
+	probe := NewPushProbe(monitor)
+	defer probe.Stop()
+	worker := &Worker{
+		ID:        1,
+		Status:    OK,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		Logger:    newTestLogger(),
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		worker.run(context.Background(), stop)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	probe.Ping(true, "")
+
+	// The ping is overdue at 1.3s. The ticker alone would not notice
+	// before 2s.
+	select {
+	case msg := <-messageCh:
+		assert.Equal(t, Critical, msg.StatusType)
+		assert.Less(t, int64(time.Since(start)), int64(1800*time.Millisecond))
+	case <-time.After(3 * time.Second):
+		t.Fatal("overdue ping not detected")
+	}
+}
+
+func TestWorker_detectFlapping(t *testing.T) {
+	worker := &Worker{
+		Monitor: &Monitor{FlapThreshold: 4, FlapWindow: Duration(10 * time.Minute)},
+	}
+	base := time.Now()
+
+	cases := []struct {
+		name        string
+		minutes     int
+		changed     bool
+		wantStarted bool
+		wantStopped bool
+	}{
+		{name: "1st change", minutes: 0, changed: true},
+		{name: "2nd change", minutes: 1, changed: true},
+		{name: "no change", minutes: 2, changed: false},
+		{name: "3rd change", minutes: 3, changed: true},
+		{name: "4th change", minutes: 4, changed: true, wantStarted: true},
+		{name: "5th change while flapping", minutes: 5, changed: true},
+		{name: "three changes left", minutes: 12, changed: false},
+		{name: "two changes left", minutes: 14, changed: false, wantStopped: true},
+		{name: "quiet", minutes: 30, changed: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			started, stopped := worker.detectFlapping(base.Add(time.Duration(c.minutes)*time.Minute), c.changed)
+
+			assert.Equal(t, c.wantStarted, started)
+			assert.Equal(t, c.wantStopped, stopped)
+		})
+	}
+}
+
+func TestWorker_detectFlapping_disabled(t *testing.T) {
+	worker := &Worker{Monitor: &Monitor{}}
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		started, stopped := worker.detectFlapping(now, true)
+		assert.False(t, started)
+		assert.False(t, stopped)
+	}
+	assert.Len(t, worker.changes, 0)
+}
+
+func TestWorker_check_flapping(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	monitor.FlapThreshold = 3
+
+	messageCh := make(chan Message, 10)
+	probe := &stubProbe{}
+	worker := &Worker{
+		ID:        1,
+		Status:    OK,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		Logger:    newTestLogger(),
+	}
+
+	cases := []struct {
+		name         string
+		ok           bool
+		wantMessage  string
+		wantFlapping bool
+	}{
+		{name: "down", ok: false, wantMessage: "CRITICAL: "},
+		{name: "up", ok: true, wantMessage: "OK: "},
+		{name: "down again", ok: false, wantMessage: "FLAPPING: ", wantFlapping: true},
+		{name: "suppressed", ok: true, wantFlapping: true},
+		{name: "still suppressed", ok: false, wantFlapping: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			probe.ok = c.ok
+			worker.check(context.Background())
+
+			assert.Equal(t, c.wantFlapping, worker.State().Flapping)
+			if c.wantMessage != "" {
+				assert.Len(t, messageCh, 1)
+				msg := <-messageCh
+				assert.True(t, strings.HasPrefix(msg.Text, c.wantMessage), msg.Text)
+			} else {
+				assert.Len(t, messageCh, 0)
+			}
+		})
+	}
+
+	// Changes older than the window no longer count.
+	for i := range worker.changes {
+		worker.changes[i] = worker.changes[i].Add(-2 * defaultFlapWindow)
+	}
+	worker.check(context.Background())
+
+	assert.False(t, worker.State().Flapping)
+	assert.Len(t, messageCh, 1)
+	msg := <-messageCh
+	assert.Equal(t, Critical, msg.StatusType)
+	assert.True(t, strings.HasPrefix(msg.Text, "STABLE: "), msg.Text)
+}
+
+func TestWorker_restoreStatus(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	cases := []struct {
+		name      string
+		monitorID int64
+		want      Status
+	}{
+		{name: "last result is OK", monitorID: 1, want: OK},
+		{name: "last result is CRITICAL", monitorID: 3, want: Critical},
+		{name: "no results", monitorID: 100, want: OK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			worker := &Worker{
+				Status:  Unknown,
+				Monitor: &Monitor{ID: c.monitorID},
+				Logger:  newTestLogger(),
+			}
+			err := worker.restoreStatus()
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, worker.Status)
+		})
+	}
+}
+
+func TestWorker_restoreStatus_check(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/follow")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+
+	messageCh := make(chan Message, 10)
+	probe := &stubProbe{ok: false, reason: "500 Internal Server Error"}
+	worker := &Worker{
+		ID:        3,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		Logger:    newTestLogger(),
+	}
+	if err := worker.restoreStatus(); err != nil {
+		t.Fatal("restore status failed:", err)
+	}
+
+	// Still failing after a restart: no new alert.
+	worker.check(context.Background())
+	assert.Equal(t, Critical, worker.Status)
+	assert.Len(t, messageCh, 0)
+
+	// Recovered: a single OK notification.
+	probe.ok, probe.reason = true, "200 OK"
+	worker.check(context.Background())
+	assert.Equal(t, OK, worker.Status)
+	assert.Len(t, messageCh, 1)
+
+	got, err := GetLastResultByMonitorID(monitor.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "OK", got.Status)
+}
+
+func TestWorker_checkCertificate(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+
+	messageCh := make(chan Message, 10)
+	probe := &HTTPProbe{Monitor: monitor}
+	worker := &Worker{
+		ID:        1,
+		Status:    OK,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		Logger:    newTestLogger(),
+	}
+
+	cases := []struct {
+		name        string
+		days        int
+		wantMessage bool
+	}{
+		{name: "not yet", days: 40, wantMessage: false},
+		{name: "below 30 days", days: 20, wantMessage: true},
+		{name: "still below 30 days", days: 19, wantMessage: false},
+		{name: "below 14 days", days: 10, wantMessage: true},
+		{name: "below 3 days", days: 1, wantMessage: true},
+		{name: "renewed", days: 90, wantMessage: false},
+		{name: "below 30 days after renewal", days: 25, wantMessage: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			notAfter := time.Now().Add(time.Duration(c.days)*24*time.Hour + time.Hour).UTC().Truncate(time.Second)
+			probe.certNotAfter = notAfter
+			worker.checkCertificate()
+
+			if c.wantMessage {
+				msg := <-messageCh
+				assert.Equal(t, Warning, msg.StatusType)
+				assert.Contains(t, msg.Text, "certificate expires in")
+			} else {
+				assert.Len(t, messageCh, 0)
+			}
+
+			got, err := GetMonitorByName("GET /monitor/get")
+			assert.Nil(t, err)
+			assert.True(t, notAfter.Equal(*got.CertNotAfter))
+		})
+	}
+}
+
+func TestWorker_restoreCertificate(t *testing.T) {
+	cleanup := prepareTestDB(t)
+	defer cleanup()
+
+	monitor, err := GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+	notAfter := time.Now().Add(20*24*time.Hour + time.Hour).UTC().Truncate(time.Second)
+	if err := UpdateMonitorCertNotAfter(monitor.ID, notAfter); err != nil {
+		t.Fatal("update certificate expiry failed:", err)
+	}
+	monitor, err = GetMonitorByName("GET /monitor/get")
+	if err != nil {
+		t.Fatal("get monitor failed:", err)
+	}
+
+	messageCh := make(chan Message, 10)
+	probe := &HTTPProbe{Monitor: monitor}
+	worker := &Worker{
+		ID:        1,
+		Status:    OK,
+		Monitor:   monitor,
+		Probe:     probe,
+		MessageCh: messageCh,
+		Logger:    newTestLogger(),
+	}
+	worker.restoreCertificate()
+
+	// The warning below 30 days was sent before the restart.
+	probe.certNotAfter = notAfter
+	worker.checkCertificate()
+	assert.Len(t, messageCh, 0)
+
+	probe.certNotAfter = notAfter.Add(-10 * 24 * time.Hour)
+	worker.checkCertificate()
+	assert.Len(t, messageCh, 1)
+}